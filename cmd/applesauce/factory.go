@@ -43,12 +43,14 @@ func main() {
 
 	graph := depfunc.NewGraph()
 
-	must(graph.AddAction("apples", growApples()))
-	must(graph.AddAction("sugars", growSugar()))
-	must(graph.AddAction("metals", recycleMetal()))
-	must(graph.AddAction("cans", formCans()))
-	must(graph.AddAction("applesauce", canApplesauce()))
-	must(graph.AddAction("qa", qa()))
+	must(graph.AddActions(map[string]depfunc.Action{
+		"apples":     growApples(),
+		"sugars":     growSugar(),
+		"metals":     recycleMetal(),
+		"cans":       formCans(),
+		"applesauce": canApplesauce(),
+		"qa":         qa(),
+	}))
 
 	must(graph.LinkDependency("metals", "cans"))
 	must(graph.LinkDependency("apples", "applesauce"))
@@ -73,22 +75,13 @@ func main() {
 	for i := 0; i < *numResolves; i++ {
 		wg.Add(1)
 		exec := func() {
-
-			stats := depfunc.NewStatistics()
-
-			var recorders []depfunc.Recorder
-			if *showStats {
-				recorders = []depfunc.Recorder{stats.Recorder()}
-			}
-
 			defer wg.Done()
 			answers := &Answers{}
-			ctx, err := graph.Resolve(ctx, answers, recorders...)
-			must(err)
-			select {
-			case <-ctx.Done():
-				fmt.Printf("%s\n", answers)
+			stats, err := graph.ResolveWithStats(ctx, answers)
+			if err != nil {
+				fmt.Printf("resolve failed: %v\n", err)
 			}
+			fmt.Printf("%s\n", answers)
 
 			if *showStats {
 				printStats(stats)
@@ -213,4 +206,6 @@ func printStats(stats *depfunc.Statistics) {
 	for name := range stats.Names() {
 		fmt.Printf("%s: wait=%v action=%v total=%v\n", name, stats.Wait(name), stats.Action(name), stats.Total(name))
 	}
+	summary := stats.Summary()
+	fmt.Printf("span=%v slowest=%s (%v) executed=%d aborted=%d\n", summary.Span, summary.Slowest, summary.SlowestAction, summary.Executed, summary.Aborted)
 }
@@ -2,16 +2,146 @@ package depfunc
 
 import (
 	"context"
+	"fmt"
+	"math/rand"
+	"sort"
 
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/pkg/errors"
 )
 
+var (
+	// ErrCycle is returned when the dependencies of a Graph form a cycle
+	ErrCycle = errors.New("cycle detected")
+
+	// ErrNoRoots is returned when every action in a Graph has a dependent,
+	// so there is nowhere to begin resolving
+	ErrNoRoots = errors.New("no roots in graph")
+
+	// ErrSkipDependents is returned by an ActionE that succeeded, but whose dependents
+	// do not need to execute, such as when a cached result made them unnecessary.
+	// The action is not considered to have failed, and every action that depends on it,
+	// directly or transitively, is skipped even if its other dependencies succeeded,
+	// since the action has not provided it anything to work with. Skipped dependents
+	// are reported to SkipRecorders. Actions added by Node.AddDependent are unaffected.
+	ErrSkipDependents = errors.New("skip dependents")
+
+	// ErrNoChange is returned by an ActionE that succeeded, but found it had nothing to do,
+	// such as a build step whose inputs were up to date. Unlike ErrSkipDependents, its
+	// dependents still execute, but they can tell it made no change with Node.Unchanged
+	// and Node.DependenciesUnchanged, to shortcut their own work. The result of a
+	// ResultAction that returns ErrNoChange is kept, so it can return its prior result.
+	// Actions that make no change are reported to NoChangeRecorders.
+	ErrNoChange = errors.New("no change")
+
+	// ErrAttemptsExhausted is reported to RetryRecorders when an Action stops
+	// being retried because it has failed as many times as its RetryPolicy allows
+	ErrAttemptsExhausted = errors.New("retry attempts exhausted")
+
+	// ErrBudgetExhausted is reported to RetryRecorders when an Action stops
+	// being retried because the time budget of its RetryPolicy has passed
+	ErrBudgetExhausted = errors.New("retry budget exhausted")
+
+	// ErrPanic is the cause of the error of an Action that panicked.
+	// The panic is recovered and the Action fails as if it had returned
+	// the error, which is reported to PanicRecorders along with the value.
+	ErrPanic = errors.New("action panicked")
+
+	// ErrStatisticsInUse is returned when a Statistics' Recorder is used by more than one resolve
+	ErrStatisticsInUse = errors.New("statistics already used by a resolve")
+
+	// ErrEmptyName is returned when an action is added or linked without a name
+	ErrEmptyName = errors.New("name must not be empty")
+
+	// ErrNilAction is returned when a nil action is added, instead of panicking once it is executed
+	ErrNilAction = errors.New("action must not be nil")
+
+	// ErrUnknownAction is the cause of the NameError returned when an action
+	// that has not been added is linked to depend on another
+	ErrUnknownAction = errors.New("action not added")
+
+	// ErrUnknownParent is the cause of the NameError returned when an action
+	// is linked to depend on another that has not been added
+	ErrUnknownParent = errors.New("parent action not added")
+
+	// ErrSuperRootDependent is the cause of the NameError returned when
+	// a super root is linked to have a dependent
+	ErrSuperRootDependent = errors.New("super root cannot have dependents")
+
+	// ErrDuplicateLink is the cause of the NameError returned by
+	// LinkDependencyStrict when the dependency already exists
+	ErrDuplicateLink = errors.New("dependency already exists")
+
+	// ErrWouldCycle is the cause of the NameError returned by
+	// LinkDependencyChecked when the dependency would create a cycle
+	ErrWouldCycle = errors.New("dependency would create a cycle")
+)
+
+// NameError is returned by LinkDependency, and the methods built on it,
+// when a name it is given does not refer to an action that can be linked.
+// Err, its cause, is one of ErrEmptyName, ErrUnknownAction, ErrUnknownParent,
+// ErrSuperRootDependent, ErrDuplicateLink or ErrWouldCycle, and Name is the name
+// at fault, so that a Graph wired up from a config file can report exactly
+// which reference was invalid. For the last three, that is the parent.
+type NameError struct {
+	Name string
+	Err  error
+}
+
+func (e *NameError) Error() string {
+	if e.Name == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s: %q", e.Err, e.Name)
+}
+
+// Cause returns Err, for errors.Cause
+func (e *NameError) Cause() error {
+	return e.Err
+}
+
+// Unwrap returns Err, for errors.Is and errors.As
+func (e *NameError) Unwrap() error {
+	return e.Err
+}
+
 // Action is a function to execute after its dependencies have been executed
 type Action func(ctx context.Context, arg interface{})
 
-// Graph is a graph of Actions to execute concurrently in dependency order
+// Simple adapts f, which needs neither the context nor the arg, to an Action
+func Simple(f func()) Action {
+	return func(ctx context.Context, arg interface{}) {
+		f()
+	}
+}
+
+// SimpleArg adapts f, which needs only the arg, to an Action
+func SimpleArg(f func(arg interface{})) Action {
+	return func(ctx context.Context, arg interface{}) {
+		f(arg)
+	}
+}
+
+// ActionE is an Action that may fail. By default an error returned from an
+// ActionE cancels the resolve (fail-fast) so that no further Actions start.
+type ActionE func(ctx context.Context, arg interface{}) error
+
+// Middleware wraps the Action of the named node with cross-cutting behavior.
+// It must call next synchronously for errors from next to be reported.
+type Middleware func(name string, next Action) Action
+
+// AsyncAction is an Action that starts work in the background and returns a
+// context that is done when that work is complete. A nil context means the
+// work completed before the action returned.
+type AsyncAction func(ctx context.Context, arg interface{}) context.Context
+
+// Graph is a graph of Actions to execute concurrently in dependency order.
+// A Graph may be changed while it is being resolved: each resolve executes a
+// snapshot of the Graph taken atomically as it begins, which later changes do
+// not affect. A Graph must not be changed by more than one goroutine at a time.
 type Graph struct {
 	// treeOrder is the adjacency list where the dependent-most node is a root
 	// applesauce < {apples, sugar}
@@ -22,164 +152,1038 @@ type Graph struct {
 	graphOrder stringmultimap
 
 	// actions is the map of actions by name
-	actions map[string]Action
+	actions map[string]ActionE
+
+	// continueOnError is the set of actions whose errors do not cancel the resolve
+	continueOnError StringSet
+
+	// tags is the map of action names to their tags
+	tags stringmultimap
+
+	// middleware is the list of Middleware to wrap actions with, outermost first
+	middleware []Middleware
+
+	// costs is the map of action names to their estimated cost
+	costs map[string]time.Duration
+
+	// subgraphs is the map of action names to the graphs they resolve, added by AddSubgraph
+	subgraphs map[string]*Graph
+
+	// superRoot is the action that depends on every other action with no dependents, if any
+	superRoot string
+
+	// order is the names of the actions in the order they were added
+	order []string
+
+	// mx guards the graph against changing while it is snapshotted
+	mx *sync.RWMutex
+
+	// snap is the snapshot of the graph that is resolved, until the graph changes
+	snap *Graph
 }
 
 // NewGraph creates a new Graph
 func NewGraph() *Graph {
 	return &Graph{
-		treeOrder:  make(stringmultimap),
-		graphOrder: make(stringmultimap),
-		actions:    make(map[string]Action),
+		treeOrder:       make(stringmultimap),
+		graphOrder:      make(stringmultimap),
+		actions:         make(map[string]ActionE),
+		continueOnError: make(StringSet),
+		tags:            make(stringmultimap),
+		costs:           make(map[string]time.Duration),
+		subgraphs:       make(map[string]*Graph),
+		mx:              &sync.RWMutex{},
+	}
+}
+
+// change locks the graph to be changed, forgetting its snapshot,
+// and returns the function that unlocks it
+func (g *Graph) change() func() {
+	g.mx.Lock()
+	g.snap = nil
+	return g.mx.Unlock
+}
+
+// snapshot returns an immutable copy of the graph, copying it
+// only if it has changed since the last snapshot was taken
+func (g *Graph) snapshot() *Graph {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+	if g.snap != nil {
+		return g.snap
 	}
+	snap := &Graph{
+		treeOrder:       g.treeOrder.copy(),
+		graphOrder:      g.graphOrder.copy(),
+		actions:         make(map[string]ActionE, len(g.actions)),
+		continueOnError: g.continueOnError.Union(nil),
+		tags:            g.tags.copy(),
+		middleware:      append([]Middleware(nil), g.middleware...),
+		costs:           make(map[string]time.Duration, len(g.costs)),
+		superRoot:       g.superRoot,
+		order:           append([]string(nil), g.order...),
+		mx:              &sync.RWMutex{},
+	}
+	for name, action := range g.actions {
+		snap.actions[name] = action
+	}
+	for name, cost := range g.costs {
+		snap.costs[name] = cost
+	}
+	snap.snap = snap
+	g.snap = snap
+	return snap
 }
 
 // AddAction adds an action to the graph
 func (g *Graph) AddAction(name string, action Action) error {
 	if name == "" {
-		return errors.New("name must not be empty")
+		return ErrEmptyName
+	}
+	if action == nil {
+		return ErrNilAction
+	}
+	return g.AddActionE(name, actionE(action))
+}
+
+// actionE adapts an Action that cannot fail to an ActionE
+func actionE(action Action) ActionE {
+	return func(ctx context.Context, arg interface{}) error {
+		action(ctx, arg)
+		return nil
+	}
+}
+
+// AddActions adds every action in actions to the graph, keyed by name.
+// If any name or action is invalid an error naming it is returned and no actions are added.
+func (g *Graph) AddActions(actions map[string]Action) error {
+	for name, action := range actions {
+		if name == "" {
+			return ErrEmptyName
+		}
+		if action == nil {
+			return errors.Wrapf(ErrNilAction, "action %q", name)
+		}
+	}
+	for name, action := range actions {
+		if err := g.AddAction(name, action); err != nil {
+			return errors.Wrapf(err, "action %q", name)
+		}
+	}
+	return nil
+}
+
+// AddActionE adds an action that may fail to the graph.
+// An error returned by the action cancels the resolve.
+func (g *Graph) AddActionE(name string, action ActionE) error {
+	if name == "" {
+		return ErrEmptyName
+	}
+	if action == nil {
+		return ErrNilAction
 	}
+	defer g.change()()
+	g.addAction(name, action)
+	return nil
+}
+
+// addAction adds or replaces the action name, forgetting how it was added before.
+// It must be called while changing the graph.
+func (g *Graph) addAction(name string, action ActionE) {
+	_, exists := g.actions[name]
 	g.actions[name] = action
+	if !exists {
+		g.order = append(g.order, name)
+	}
+	if !exists && g.superRoot != "" && name != g.superRoot {
+		g.treeOrder.Add(g.superRoot, name)
+		g.graphOrder.Add(name, g.superRoot)
+	}
+	g.continueOnError.Remove(name)
+	delete(g.tags, name)
+	delete(g.costs, name)
+	delete(g.subgraphs, name)
+}
+
+// AddActionContinueOnError adds a best-effort action to the graph.
+// An error returned by the action is reported to ErrorRecorders as swallowed,
+// but it does not cancel the resolve and its dependents still run, unless the
+// action's own context ended first, such as by WithActionTimeout.
+// This takes precedence over the fail-fast behavior of AddActionE:
+// a continue-on-error action can never cancel a resolve, but it is still
+// aborted like any other action if another action fails first.
+func (g *Graph) AddActionContinueOnError(name string, action ActionE) error {
+	if name == "" {
+		return ErrEmptyName
+	}
+	if action == nil {
+		return ErrNilAction
+	}
+	defer g.change()()
+	g.addAction(name, action)
+	g.continueOnError.Add(name)
+	return nil
+}
+
+// AddActionWithTags adds an action to the graph labeled with tags.
+// Tags do not affect execution, but they can be used to group and filter actions.
+func (g *Graph) AddActionWithTags(name string, action Action, tags ...string) error {
+	if name == "" {
+		return ErrEmptyName
+	}
+	if action == nil {
+		return ErrNilAction
+	}
+	defer g.change()()
+	g.addAction(name, actionE(action))
+	for _, tag := range tags {
+		g.tags.Add(name, tag)
+	}
+	return nil
+}
+
+// AddActionWithCost adds an action to the graph with an estimate of how long it takes to execute.
+// Costs do not affect execution, but they are used by EstimatedCriticalPath.
+func (g *Graph) AddActionWithCost(name string, action Action, cost time.Duration) error {
+	if name == "" {
+		return ErrEmptyName
+	}
+	if action == nil {
+		return ErrNilAction
+	}
+	defer g.change()()
+	g.addAction(name, actionE(action))
+	g.costs[name] = cost
 	return nil
 }
 
+// ActionsByTag returns the sorted names of all actions labeled with tag
+func (g *Graph) ActionsByTag(tag string) []string {
+	var names []string
+	for name, tags := range g.tags {
+		if tags.Contains(tag) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AddAsyncAction adds an action that completes asynchronously to the graph.
+// The action is finished, and its dependents may run, once the context
+// it returns is done. No goroutine waits for it in the meantime, and it does
+// not take up a slot of an executor or Scheduler, but Serial still executes
+// nothing else until it is done. If the context the action was given is done
+// first, such as when the resolve is cancelled or the action times out, the
+// action fails with its error, and the background work is left to observe it.
+// Hooks given by WithAfterEach are called once the action is finished.
+func (g *Graph) AddAsyncAction(name string, action AsyncAction) error {
+	if name == "" {
+		return ErrEmptyName
+	}
+	if action == nil {
+		return ErrNilAction
+	}
+	return g.AddActionE(name, func(ctx context.Context, arg interface{}) error {
+		complete := action(ctx, arg)
+		if complete == nil {
+			return nil
+		}
+		if node, ok := NodeFromContext(ctx); ok {
+			node.completeWith(complete)
+			return nil
+		}
+		// Executed outside of a resolve, there is no Node to finish it later
+		select {
+		case <-complete.Done():
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// Use wraps every action in this graph, including those added later,
+// with mw when the graph is resolved. Middleware is applied in the
+// order it was added, so the first Middleware used is the outermost.
+func (g *Graph) Use(mw Middleware) {
+	defer g.change()()
+	g.middleware = append(g.middleware, mw)
+}
+
+// wrap applies the middleware of this graph to the named action
+func (g *Graph) wrap(name string, action ActionE) ActionE {
+	if len(g.middleware) == 0 {
+		return action
+	}
+	return func(ctx context.Context, arg interface{}) error {
+		var err error
+		next := Action(func(ctx context.Context, arg interface{}) {
+			err = action(ctx, arg)
+		})
+		for i := len(g.middleware) - 1; i >= 0; i-- {
+			next = g.middleware[i](name, next)
+		}
+		next(ctx, arg)
+		return err
+	}
+}
+
 // LinkDependency creates a dependency between two actions
 func (g *Graph) LinkDependency(parent, name string) error {
 	if name == "" {
-		return errors.New("name must not be empty")
+		return &NameError{Err: ErrEmptyName}
 	}
 	if _, exists := g.actions[name]; !exists {
-		return errors.New("action not added")
+		return &NameError{Name: name, Err: ErrUnknownAction}
 	}
 	if parent == "" {
-		return errors.New("parent name must not be empty")
+		return errors.WithMessage(&NameError{Err: ErrEmptyName}, "parent")
 	}
 	if _, exists := g.actions[parent]; !exists {
-		return errors.New("parent action not added")
+		return &NameError{Name: parent, Err: ErrUnknownParent}
+	}
+	if parent == g.superRoot {
+		return &NameError{Name: parent, Err: ErrSuperRootDependent}
 	}
+	defer g.change()()
 	g.treeOrder.Add(name, parent)
 	g.graphOrder.Add(parent, name)
+	if name != g.superRoot && g.graphOrder[parent].Contains(g.superRoot) {
+		// parent is no longer a final output
+		g.treeOrder.Remove(g.superRoot, parent)
+		g.graphOrder.Remove(parent, g.superRoot)
+	}
+	return nil
+}
+
+// AddSuperRoot adds an action that depends on every other action with no dependents,
+// the final outputs of the graph, so that it executes once, after everything else.
+// The links are kept up to date as the graph changes: an action added later is
+// a final output until it gets a dependent of its own, which takes its place.
+// The super root itself cannot have dependents, and a graph has at most one.
+func (g *Graph) AddSuperRoot(name string, action Action) error {
+	if name == "" {
+		return ErrEmptyName
+	}
+	if action == nil {
+		return ErrNilAction
+	}
+	defer g.change()()
+	if g.superRoot != "" {
+		return errors.Errorf("graph already has super root %q", g.superRoot)
+	}
+	if _, exists := g.actions[name]; exists {
+		return errors.Errorf("action %q already exists", name)
+	}
+	outputs := g.collectRoots(context.Background())
+	g.addAction(name, actionE(action))
+	g.superRoot = name
+	for output := range outputs {
+		g.treeOrder.Add(name, output)
+		g.graphOrder.Add(output, name)
+	}
 	return nil
 }
 
-// Resolve executes this Graph on a given context.
+// LinkDependencyStrict creates a dependency between two actions like LinkDependency,
+// but returns an error caused by ErrDuplicateLink if the dependency already exists
+// instead of ignoring it.
+func (g *Graph) LinkDependencyStrict(parent, name string) error {
+	if g.treeOrder[name].Contains(parent) {
+		return errors.WithMessagef(&NameError{Name: parent, Err: ErrDuplicateLink}, "linking %s->%s", parent, name)
+	}
+	return g.LinkDependency(parent, name)
+}
+
+// LinkDependencyChecked creates a dependency between two actions like LinkDependency,
+// but returns an error caused by ErrWouldCycle instead if the dependency would create
+// a cycle, because parent already depends on name, directly or transitively.
+func (g *Graph) LinkDependencyChecked(parent, name string) error {
+	if name != "" && parent == name {
+		return errors.WithMessagef(&NameError{Name: parent, Err: ErrWouldCycle}, "linking %s->%s", parent, name)
+	}
+	if dependencies, err := g.TransitiveDependencies(parent); err == nil {
+		if i := sort.SearchStrings(dependencies, name); i < len(dependencies) && dependencies[i] == name {
+			return errors.WithMessagef(&NameError{Name: parent, Err: ErrWouldCycle}, "linking %s->%s", parent, name)
+		}
+	}
+	return g.LinkDependency(parent, name)
+}
+
+// Resolve executes this Graph on a given context, monitored by recorders.
 // A child context is returned that is done when the
 // Actions are all executed or an error occurs.
+// It is shorthand for ResolveWith and WithRecorders.
 func (g *Graph) Resolve(ctx context.Context, arg interface{}, recorders ...Recorder) (context.Context, error) {
-	// Create a sub-context in which to execute the Actions in this Graph
-	ctx, done := context.WithCancel(ctx)
+	return g.ResolveWith(ctx, arg, WithRecorders(recorders...))
+}
+
+// ResolveWith executes this Graph on a given context, configured by opts.
+// A child context is returned that is done when the
+// Actions are all executed or an error occurs.
+// context.Cause of the child context is the error that ended the resolve
+// early, such as a cycle or the error of the Action that failed, or
+// context.Canceled if the Actions were all executed. When ResolveWith
+// returns an error, the child context is already cancelled with it.
+func (g *Graph) ResolveWith(ctx context.Context, arg interface{}, opts ...ResolveOption) (context.Context, error) {
+	s, err := g.resolve(ctx, arg, newResolveConfig(opts))
+	return s.ctx, err
+}
+
+// ResolveArgs executes this Graph on a given context like ResolveWith,
+// but each Action receives its own arg from args, called with the Action's name,
+// instead of every Action sharing a single arg.
+func (g *Graph) ResolveArgs(ctx context.Context, args func(name string) interface{}, opts ...ResolveOption) (context.Context, error) {
+	return g.ResolveWith(ctx, nil, append(opts, WithArgs(args))...)
+}
+
+// ResolveResume executes this Graph on a given context, configured by opts,
+// executing only the Actions that did not finish successfully in the resolve
+// recorded by prior. It is shorthand for ResolveWith and WithResume.
+func (g *Graph) ResolveResume(ctx context.Context, arg interface{}, prior *Statistics, opts ...ResolveOption) (context.Context, error) {
+	return g.ResolveWith(ctx, arg, append(opts, WithResume(prior))...)
+}
+
+// ResolveCancel executes this Graph on a given context, configured by opts.
+// A child context is returned that is done when the Actions are all executed
+// or an error occurs, along with a function that aborts the resolve.
+// Once the cancel function returns, no further Actions will start.
+func (g *Graph) ResolveCancel(ctx context.Context, arg interface{}, opts ...ResolveOption) (context.Context, context.CancelFunc, error) {
+	s, err := g.resolve(ctx, arg, newResolveConfig(opts))
+	return s.ctx, s.abort, err
+}
+
+// resolve begins executing this Graph and returns the search that is executing it.
+// The search's finished channel is closed once every visited action has exited,
+// whether the resolve succeeded or not.
+func (g *Graph) resolve(ctx context.Context, arg interface{}, cfg *resolveConfig) (search, error) {
+	// Resolve a snapshot, so that changing the graph does not affect the resolve
+	g = g.snapshot()
+
+	// Bound the resolve by its deadline, if it has one
+	releaseDeadline := context.CancelFunc(func() {})
+	if deadline, ok := cfg.deadline(); ok {
+		ctx, releaseDeadline = context.WithDeadline(ctx, deadline)
+	}
+
+	// Create a sub-context in which to execute the Actions in this Graph,
+	// cancelled with the reason the resolve ended
+	ctx, done := context.WithCancelCause(ctx)
 
-	// Initialize our search data
+	// Initialize our search data, sized for a traversal of the whole graph
+	// unless the resolve is already cancelled and nothing will be traversed
+	size := len(g.actions)
+	if ctx.Err() != nil {
+		size = 0
+	}
 	s := search{
-		waits:   make(map[string]*sync.WaitGroup),
-		visited: make(StringSet),
-		path:    make(StringSet),
-		ctx:     ctx,
-		wg:      &sync.WaitGroup{},
-		dfsWait: &sync.WaitGroup{},
-		arg:     arg,
+		ctx:       ctx,
+		done:      done,
+		graph:     g,
+		mx:        &sync.RWMutex{},
+		wg:        &sync.WaitGroup{},
+		dfsWait:   &sync.WaitGroup{},
+		finished:  make(chan struct{}),
+		skipped:   g.skipped(cfg),
+		added:     newAddedActions(),
+		failed:    NewSyncStringSet(),
+		pruned:    NewSyncStringSet(),
+		unchanged: NewSyncStringSet(),
+		timeouts:  cfg.timeouts,
+		gates:     cfg.gates,
+		retries:   cfg.retries,
+		executor:  cfg.executor,
+		resumed:   cfg.resumed,
+		shuffle:   cfg.shuffle(),
+		outcome:   &outcome{mx: &sync.Mutex{}},
+		results:   newResultMap(),
+		begun:     time.Now(),
+		ended:     new(time.Time),
+		nodes:     newNodeCancels(),
+		values:    newNodeValues(),
+		until:     cfg.until,
+		overrides: cfg.overrides,
+		before:    cfg.beforeEach,
+		after:     cfg.afterEach,
+		stopped:   new(int32),
+		stopping:  cfg.untilCancels,
+		pause:     newPauseGate(),
+		sorted:    cfg.sorted,
+		args:      cfg.argsOr(arg),
+	}
+	if cfg.insertionOrder || cfg.serial {
+		s.rank = g.rank()
+	}
+	if cfg.scratch != nil {
+		cfg.scratch.reset(size)
+		s.scratch = cfg.scratch
+		s.waits, s.visited = cfg.scratch.waits, cfg.scratch.visited
+		s.path, s.checked = cfg.scratch.path, cfg.scratch.checked
+		s.started, s.completed = cfg.scratch.started, cfg.scratch.completed
+	} else {
+		s.waits = make(map[string]*sync.WaitGroup, size)
+		s.visited = make(StringSet, size)
+		s.path = make(StringSet)
+		s.checked = make(StringSet, size)
+		s.started = NewSyncStringSet()
+		s.completed = NewSyncStringSet()
+	}
+	if cfg.serial {
+		s.serial = newSerialQueue(s.rank)
+		s.executor = nil
+	}
+	if cfg.lazy || cfg.serial {
+		s.parked = make(map[string]*parkedAction, size)
+	} else if cfg.eager {
+		s.planned = &[]plannedLaunch{}
 	}
 
-	recorder := optionalRecorder(recorders...)
+	recorder := optionalRecorder(cfg.identifiedRecorders()...)
 
 	s.dfsWait.Add(1)
 	defer s.dfsWait.Done()
 
-	// Begin DFS on each root.
-	rootFound := false
-	for root := range g.collectRoots() {
-		rootFound = true
-		if err := g.dfsResolve(s, "", root, recorder); err != nil {
-			done()
-			return ctx, err
+	release, err := claimRecorders(cfg.recorders)
+	if err == nil {
+		err = g.searchRoots(s, recorder)
+	}
+	if err == ErrNoRoots && cfg.allowEmpty {
+		err = nil
+	} else if err == nil && !cfg.pruneDeadBranches {
+		err = g.checkDeadBranches(s)
+	}
+	if err != nil {
+		done(err)
+		// A resolve that fails to set up does not keep its recorders: at once
+		// if it visited nothing, or else once what it visited has exited
+		if len(s.visited) == 0 {
+			release()
+			release = func() {}
+		}
+	}
+	g.launchReady(s, recorder)
+	g.launchPlanned(s, recorder)
+	if s.serial != nil {
+		s.serial.start()
+	}
+
+	finish := func() {
+		// If the context is done before every action finished, the resolve was cut short
+		s.outcome.set(s.ctx.Err())
+		if err != nil {
+			release()
 		}
+		done(nil)
+		releaseDeadline()
+		*s.ended = time.Now()
+		close(s.finished)
 	}
 
-	if !rootFound {
-		done()
-		return ctx, errors.New("no roots in graph")
+	// An empty resolve is finished before it is returned
+	if len(s.visited) == 0 && err == nil {
+		finish()
+		return s, nil
 	}
 
-	// Wait for all visits to finish, no errors occurred
-	// after our DFS, so we are just waiting for execution to finish.
+	// Wait for all visits to finish. If no errors occurred during
+	// our DFS, we are just waiting for execution to finish.
 	go func() {
 		s.wg.Wait()
-		done()
+		finish()
 	}()
 
-	return ctx, nil
+	return s, err
 }
 
-// dfsResolve will kick of a goroutine for each of our actions.
-// Each goroutine will be waiting for its dependencies to complete, so a full
-// traversal may be made before any Actions are run.
-func (g *Graph) dfsResolve(s search, parent, name string, recorder Recorder) error {
-	//if s.searchContextDone() {
-	//	return nil
-	//}
+// searchRoots begins the DFS on each root
+func (g *Graph) searchRoots(s search, recorder Recorder) error {
+	if s.searchContextDone() {
+		return nil
+	}
+	if len(g.treeOrder) == 0 {
+		return g.searchEdgeless(s, recorder)
+	}
 
-	s.visited.Add(name)
-	s.path.Add(name)
+	roots := g.collectRoots(s.ctx)
+	if s.searchContextDone() {
+		return nil
+	}
+	if len(roots) == 0 {
+		return ErrNoRoots
+	}
+	if err := g.checkCycles(s, roots); err != nil || s.searchContextDone() {
+		return err
+	}
 
-	g.visit(s, name, recorder)
+	return s.each(roots, func(root string) error {
+		return g.dfsResolve(s, "", root, recorder)
+	})
+}
 
-	for child := range g.treeOrder[name] {
-		if s.path.Contains(child) {
-			return errors.New("cycle detected")
-		}
-		if s.visited.Contains(child) {
-			continue
+// checkCycles returns ErrCycle if the dependencies of any of roots form a cycle,
+// or stops early if the resolve is done. It is a single-threaded pass of its own
+// before the actions are set up, so that the setup needs no cycle detection and
+// does not depend on visiting actions in any particular order or on a single thread.
+func (g *Graph) checkCycles(s search, roots StringSet) error {
+	checked := 0
+	var visit func(name string) bool
+	visit = func(name string) bool {
+		if checked++; checked%1024 == 0 && s.searchContextDone() {
+			return false
 		}
-		if !s.searchContextDone() {
-			if err := g.dfsResolve(s, name, child, recorder); err != nil {
-				return err
+		s.path.Add(name)
+		for dep := range g.treeOrder[name] {
+			if s.path.Contains(dep) {
+				return true
 			}
+			if !s.checked.Contains(dep) && !s.resumed.Contains(dep) && visit(dep) {
+				return true
+			}
+		}
+		s.path.Remove(name)
+		s.checked.Add(name)
+		return false
+	}
+	for root := range roots {
+		if !s.resumed.Contains(root) && visit(root) {
+			return ErrCycle
 		}
 	}
+	return nil
+}
 
-	s.path.Remove(name)
+// checkDeadBranches returns an error naming the dead branches of the Graph, if it has any.
+// Every action is visited by a search of a Graph without dead branches, so they are
+// only looked for when some were not, and the search was not cut short.
+func (g *Graph) checkDeadBranches(s search) error {
+	if len(s.visited) == len(g.actions) || s.searchContextDone() {
+		return nil
+	}
+	if dead := g.DeadBranches(); len(dead) > 0 {
+		return deadBranchesError(dead)
+	}
 	return nil
 }
 
+// searchEdgeless visits every action of a Graph without links, each of which is a root,
+// launching them directly without the DFS or the wait groups that order dependencies
+func (g *Graph) searchEdgeless(s search, recorder Recorder) error {
+	if len(g.actions) == 0 {
+		return ErrNoRoots
+	}
+
+	roots := make(StringSet, len(g.actions))
+	for name := range g.actions {
+		roots.Add(name)
+	}
+
+	// Nothing has dependencies, so every action shares a wait that is already done
+	ready := &sync.WaitGroup{}
+	return s.each(roots, func(name string) error {
+		if s.searchContextDone() || s.resumed.Contains(name) {
+			return nil
+		}
+		s.visited.Add(name)
+		if s.skipped.Contains(name) {
+			recordSkip(recorder, name)
+			return nil
+		}
+		action := g.wrap(name, s.action(g, name))
+		if s.parked != nil {
+			g.park(s, name, action, 0, recorder)
+			return nil
+		}
+		g.schedule(s, name, action, ready, recorder)
+		return nil
+	})
+}
+
+// dfsResolve will kick of a goroutine for each of our actions.
+// Each goroutine will be waiting for its dependencies to complete, so a full
+// traversal may be made before any Actions are run.
+func (g *Graph) dfsResolve(s search, parent, name string, recorder Recorder) error {
+	// Abort setup promptly if the context is already done
+	if s.searchContextDone() {
+		return nil
+	}
+
+	// Actions done in a prior resolve need neither executing nor their dependencies
+	if s.resumed.Contains(name) {
+		return nil
+	}
+
+	s.visited.Add(name)
+
+	if s.skipped.Contains(name) {
+		recordSkip(recorder, name)
+	} else {
+		g.visit(s, name, recorder)
+	}
+
+	return s.each(g.treeOrder[name], func(child string) error {
+		if s.visited.Contains(child) || s.searchContextDone() {
+			return nil
+		}
+		return g.dfsResolve(s, name, child, recorder)
+	})
+}
+
 // visit visits a node in the graph, executing the action for the given name
 func (g *Graph) visit(s search, name string, recorder Recorder) {
-	action := g.actions[name]
+	action := g.wrap(name, s.action(g, name))
+
+	pending := len(g.treeOrder[name].Difference(s.resumed))
+	if s.parked != nil {
+		g.park(s, name, action, pending, recorder)
+		return
+	}
+	wg := s.createWaitGroupForDependents(name, pending)
+
+	g.schedule(s, name, action, wg, recorder)
+}
 
-	children := g.treeOrder[name]
-	wg := s.createWaitGroupForDependents(name, len(children))
+// schedule launches an action found by the search, or if starting eagerly,
+// plans to launch it once the search is complete
+func (g *Graph) schedule(s search, name string, action ActionE, wg *sync.WaitGroup, recorder Recorder) {
+	if s.planned == nil {
+		g.launch(s, name, action, wg, recorder)
+		return
+	}
+	recorder.Enter(name)
+	s.wg.Add(1)
+	*s.planned = append(*s.planned, plannedLaunch{name: name, action: action, wg: wg})
+}
+
+// launch starts the goroutine for an action that executes once the dfs is complete and wg is done
+func (g *Graph) launch(s search, name string, action ActionE, wg *sync.WaitGroup, recorder Recorder) {
+	// Enter is recorded as the action is visited, so it is
+	// reproducible when the traversal order is
+	recorder.Enter(name)
 
 	s.wg.Add(1)
 	go func() {
-		recorder.Enter(name)
-		defer recorder.Exit(name)
-
-		parents := g.graphOrder[name]
-		defer s.visitComplete(name, parents)
 		s.dfsWait.Wait()
-		if s.searchContextDone() {
-			return
-		}
+		g.run(s, name, action, wg, recorder)
+	}()
+}
+
+// launchPlanned starts the goroutine for every action planned by schedule, without
+// waiting for the others to be started. A dependency is visited after its dependents,
+// so they are started in reverse, so that the actions that are ready start first.
+func (g *Graph) launchPlanned(s search, recorder Recorder) {
+	if s.planned == nil {
+		return
+	}
+	planned := *s.planned
+	for i := len(planned) - 1; i >= 0; i-- {
+		p := planned[i]
+		go g.run(s, p.name, p.action, p.wg, recorder)
+	}
+}
+
+// run executes an action once wg is done, or aborts it if the resolve is done
+func (g *Graph) run(s search, name string, action ActionE, wg *sync.WaitGroup, recorder Recorder) {
+	if !s.searchContextDone() {
 		wg.Wait()
-		if !s.searchContextDone() {
-			recorder.Start(name)
-			action(s.ctx, s.arg)
-			recorder.Finish(name)
+	}
+	// Hold the action back while the resolve is paused, before it
+	// takes up a slot of the executor
+	s.pause.wait(s.ctx)
+	if s.executor == nil {
+		g.execute(s, name, action, recorder)
+		return
+	}
+	s.executor(func() {
+		g.execute(s, name, action, recorder)
+	})
+}
+
+// park records an action to be launched by launchReady or once its pending dependencies have exited
+func (g *Graph) park(s search, name string, action ActionE, pending int, recorder Recorder) {
+	recorder.Enter(name)
+
+	s.wg.Add(1)
+	s.parked[name] = &parkedAction{action: action, pending: int32(pending)}
+}
+
+// launchReady launches every parked action with no pending dependencies once the dfs is complete,
+// or every parked action if the resolve is already done since their dependencies may never be visited
+func (g *Graph) launchReady(s search, recorder Recorder) {
+	aborted := s.searchContextDone()
+	names := make(StringSet, len(s.parked))
+	for name := range s.parked {
+		names.Add(name)
+	}
+	s.each(names, func(name string) error {
+		if parked := s.parked[name]; aborted || atomic.LoadInt32(&parked.pending) == 0 {
+			g.unpark(s, name, parked, recorder)
 		}
-	}()
+		return nil
+	})
 }
 
-// collectRoots collects the name of all actions that have no dependencies
-func (g *Graph) collectRoots() <-chan string {
-	ch := make(chan string)
+// unpark launches a parked action, if it has not already been launched
+func (g *Graph) unpark(s search, name string, parked *parkedAction, recorder Recorder) {
+	if !atomic.CompareAndSwapInt32(&parked.launched, 0, 1) {
+		return
+	}
+	task := func() {
+		g.execute(s, name, parked.action, recorder)
+	}
+	if s.serial != nil {
+		s.serial.push(name, func() {
+			s.pause.wait(s.ctx)
+			task()
+		})
+		return
+	}
+	if s.executor == nil {
+		go func() {
+			s.pause.wait(s.ctx)
+			task()
+		}()
+		return
+	}
+	// unpark is called by the dependency that finished last, which may hold
+	// a slot of the executor, so a paused action waits on a goroutine of its own
+	if !s.pause.isPaused() {
+		s.executor(task)
+		return
+	}
 	go func() {
-		for name := range g.actions {
-			if len(g.graphOrder[name]) == 0 {
-				ch <- name
-			}
+		s.pause.wait(s.ctx)
+		s.executor(task)
+	}()
+}
+
+// execute performs an action whose dependencies are satisfied, unless the resolve is done,
+// and signals its completion
+func (g *Graph) execute(s search, name string, action ActionE, recorder Recorder) {
+	node := &Node{name: name, g: g, s: s, recorder: recorder, mx: &sync.Mutex{}}
+
+	// Exit is recorded before completion is signalled,
+	// so every Exit happens before the resolve is done
+	parents := g.graphOrder[name]
+	exit := func() {
+		recorder.Exit(name)
+		s.visitComplete(name, parents, node)
+	}
+	// detached is set once the action is left to complete in the background
+	detached := false
+	defer func() {
+		if !detached {
+			exit()
+		}
+	}()
+
+	dependencies := s.dependencies(g, name)
+	if containsAny(s.failed, dependencies) {
+		s.failed.Add(name)
+		recordAbort(recorder, name)
+		return
+	}
+	if containsAny(s.pruned, dependencies) {
+		s.pruned.Add(name)
+		recordSkip(recorder, name)
+		return
+	}
+	if s.nodes.isCancelled(name) {
+		s.pruned.Add(name)
+		recordSkip(recorder, name)
+		return
+	}
+	if !s.searchContextDone() && !s.allow(name) {
+		s.pruned.Add(name)
+		recordGate(recorder, name)
+		recordSkip(recorder, name)
+		return
+	}
+	if !s.begin(name) {
+		if s.isStopped() {
+			s.pruned.Add(name)
+			recordSkip(recorder, name)
+		}
+		return
+	}
+
+	ctx, cancel := g.actionContext(s, name)
+	defer func() {
+		if !detached {
+			cancel()
+		}
+	}()
+	s.nodes.register(name, cancel)
+	node.inherited = s.values.merge(dependencies)
+
+	recorder.Start(name)
+	ctx = withNode(withValues(ctx, node.inherited), node)
+	for _, before := range s.before {
+		before(ctx, name)
+	}
+	err := g.attempt(s, ctx, name, action, recorder)
+	if complete := node.takeCompletion(); complete != nil && err == nil {
+		// The action finishes once its background work is done, without
+		// holding this goroutine, or a slot of the executor, until then
+		detached = true
+		finished := make(chan struct{})
+		awaitCompletion(ctx, complete, func(err error) {
+			g.complete(s, ctx, name, node, err, recorder)
+			cancel()
+			exit()
+			close(finished)
+		})
+		if s.serial != nil {
+			// Serial actions execute one at a time, background work included
+			<-finished
+		}
+		return
+	}
+	g.complete(s, ctx, name, node, err, recorder)
+}
+
+// complete handles the outcome of an action that has returned, or whose background work is done
+func (g *Graph) complete(s search, ctx context.Context, name string, node *Node, err error, recorder Recorder) {
+	for i := len(s.after) - 1; i >= 0; i-- {
+		s.after[i](ctx, name, err)
+	}
+	node.seal()
+	s.values.publish(name, node.passed())
+	if s.nodes.unregister(name) {
+		// Cancelled by CancelNode, which only its dependents are affected by
+		s.pruned.Add(name)
+		return
+	}
+	switch errors.Cause(err) {
+	case ErrSkipDependents:
+		s.pruned.Add(name)
+		err = nil
+	case ErrNoChange:
+		s.unchanged.Add(name)
+		recordNoChange(recorder, name)
+		err = nil
+	}
+	if ctx.Err() != nil && s.ctx.Err() == nil {
+		// The action's own context ended, so its dependents cannot rely on it
+		s.failed.Add(name)
+		if ctx.Err() == context.DeadlineExceeded {
+			recordTimeout(recorder, name)
+		}
+		if err == nil {
+			err = ctx.Err()
+		}
+	}
+	if err != nil {
+		g.actionFailed(s, name, err, recorder)
+	}
+	recorder.Finish(name)
+	if err == nil && name == s.until {
+		s.stop()
+	}
+}
+
+// awaitCompletion calls done once complete is done, or with the error of ctx if it is
+// done first. No goroutine is held until then, unless complete is of a custom type.
+func awaitCompletion(ctx, complete context.Context, done func(err error)) {
+	either, stop := context.WithCancel(ctx)
+	stopComplete := context.AfterFunc(complete, stop)
+	context.AfterFunc(either, func() {
+		stopComplete()
+		stop()
+		done(ctx.Err())
+	})
+}
+
+// attempt executes action, and while it fails, executes it again as allowed by its RetryPolicy.
+// It is not retried once its context is done, or if it returns ErrSkipDependents or ErrNoChange.
+func (g *Graph) attempt(s search, ctx context.Context, name string, action ActionE, recorder Recorder) error {
+	policy, retried := s.retries[name]
+	start := time.Now()
+	err := g.invoke(s, ctx, name, action, recorder)
+	if !retried {
+		return err
+	}
+	for attempt := 2; !succeeded(err) && ctx.Err() == nil; attempt++ {
+		if policy.Attempts > 0 && attempt > policy.Attempts {
+			recordGiveUp(recorder, name, ErrAttemptsExhausted)
+			break
+		}
+		if policy.Budget > 0 && time.Since(start) >= policy.Budget {
+			recordGiveUp(recorder, name, ErrBudgetExhausted)
+			break
+		}
+		recordRetry(recorder, name, attempt, err)
+		err = g.invoke(s, ctx, name, action, recorder)
+	}
+	return err
+}
+
+// succeeded returns if err is nil or one of the errors an Action returns without failing
+func succeeded(err error) bool {
+	switch errors.Cause(err) {
+	case nil, ErrSkipDependents, ErrNoChange:
+		return true
+	}
+	return false
+}
+
+// invoke executes action, recovering a panic as an error caused by ErrPanic
+func (g *Graph) invoke(s search, ctx context.Context, name string, action ActionE, recorder Recorder) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			recordPanic(recorder, name, v)
+			err = errors.Wrapf(ErrPanic, "%v", v)
 		}
-		close(ch)
 	}()
-	return ch
+	return action(ctx, s.args(name))
+}
+
+// actionContext returns the context an action executes in, bounded by its timeout if it has one
+func (g *Graph) actionContext(s search, name string) (context.Context, context.CancelFunc) {
+	if timeout, ok := s.timeouts[name]; ok {
+		return context.WithTimeout(s.ctx, timeout)
+	}
+	return context.WithCancel(s.ctx)
+}
+
+// actionFailed handles an error returned by an action, cancelling
+// the resolve unless the action was added as continue-on-error
+func (g *Graph) actionFailed(s search, name string, err error, recorder Recorder) {
+	if g.continueOnError.Contains(name) {
+		recordSwallow(recorder, name, err)
+		return
+	}
+	recordError(recorder, name, err)
+	// Its dependents are aborted, rather than left to find the resolve cancelled
+	s.failed.Add(name)
+	err = errors.Wrapf(err, "action %q", name)
+	s.outcome.set(err)
+	s.cancel(err)
+}
+
+// collectRoots collects every action with no dependents,
+// or stops early with whatever it has collected if ctx is done
+func (g *Graph) collectRoots(ctx context.Context) StringSet {
+	roots := make(StringSet)
+	checked := 0
+	for name := range g.actions {
+		if checked++; checked%1024 == 0 && ctx.Err() != nil {
+			return roots
+		}
+		if len(g.graphOrder[name]) == 0 {
+			roots.Add(name)
+		}
+	}
+	return roots
+}
+
+// rank returns the map of actions to the order they were added in
+func (g *Graph) rank() map[string]int {
+	rank := make(map[string]int, len(g.order))
+	for i, name := range g.order {
+		rank[name] = i
+	}
+	return rank
 }
 
 // search contains data used during the DFS of resolving Graph actions in Resolve
@@ -187,34 +1191,314 @@ type search struct {
 	// ctx is the context in which actions are performed
 	ctx context.Context
 
-	// waits is the map of actions to a wait group waiting for dependencies to be resolved
+	// graph is the snapshot of the Graph being resolved
+	graph *Graph
+
+	// done cancels ctx with a cause
+	done context.CancelCauseFunc
+
+	// mx serializes actions beginning with the resolve aborting
+	mx *sync.RWMutex
+
+	// waits is the map of actions to a wait group waiting for dependencies to be resolved.
+	// It is only written by the dfs, and only read by actions once they have exited, so it
+	// is not guarded: no action executes until the dfs is complete, which happens before
+	// any goroutine reads it, either by waiting on dfsWait, or by being started after the
+	// dfs, as planned and parked actions are. parked is read and written the same way.
 	waits map[string]*sync.WaitGroup
 
 	// visited is the set of visited actions
 	visited StringSet
 
-	// recursion is the stack of the currently visited path for cycle detection
+	// path is the stack of the currently visited path for cycle detection
 	path StringSet
 
+	// checked is the set of actions whose dependencies are known not to form a cycle
+	checked StringSet
+
 	// wg is the wait that signifies that Resolve is complete
 	wg *sync.WaitGroup
 
 	// wg is the wait that signifies that the dfs is complete
 	dfsWait *sync.WaitGroup
 
-	// arg is the Resolve argument
-	arg interface{}
+	// finished is closed when every visited action has exited
+	finished chan struct{}
+
+	// skipped is the set of actions that will not be executed
+	skipped StringSet
+
+	// started is the set of actions that have begun execution
+	started *SyncStringSet
+
+	// completed is the set of actions whose goroutines have finished or aborted
+	completed *SyncStringSet
+
+	// added is the actions added while resolving by Node.AddDependent
+	added *addedActions
+
+	// outcome is the error that ended the resolve early, if any
+	outcome *outcome
+
+	// sorted is whether actions are traversed in sorted order
+	sorted bool
+
+	// rank is the map of actions to the order they were added to the graph,
+	// if they are traversed in that order
+	rank map[string]int
+
+	// serial executes ready actions one at a time, if not nil
+	serial *serialQueue
+
+	// shuffle randomizes the order actions are traversed in, if not nil
+	shuffle *rand.Rand
+
+	// args returns the Resolve argument for an action
+	args func(name string) interface{}
+
+	// failed is the set of actions whose dependents must not execute, because they
+	// failed, their context ended before the resolve's or a dependency failed
+	failed *SyncStringSet
+
+	// pruned is the set of actions whose dependents must be skipped,
+	// because they returned ErrSkipDependents or a dependency was skipped this way
+	pruned *SyncStringSet
+
+	// unchanged is the set of actions that returned ErrNoChange
+	unchanged *SyncStringSet
+
+	// timeouts is the map of actions to how long they may execute
+	timeouts map[string]time.Duration
+
+	// resumed is the set of actions that finished in a prior resolve
+	resumed StringSet
+
+	// executor runs actions whose dependencies are satisfied, if not on their own goroutines
+	executor func(task func())
+
+	// parked is the map of actions waiting to be launched, if launching lazily
+	parked map[string]*parkedAction
+
+	// scratch provides the wait groups of actions, if not nil
+	scratch *Scratch
+
+	// gates decide whether each action may execute
+	gates []Gate
+
+	// planned are the actions to launch once the dfs is complete, if starting eagerly
+	planned *[]plannedLaunch
+
+	// results are the results returned by ResultActions
+	results *resultMap
+
+	// nodes cancels the contexts of individual executing actions
+	nodes *nodeCancels
+
+	// values are the context values actions pass to their dependents
+	values *nodeValues
+
+	// retries is the map of actions to how they are retried when they fail
+	retries map[string]RetryPolicy
+
+	// overrides is the map of actions executed in place of those of the graph
+	overrides map[string]ActionE
+
+	// before are called on each action's goroutine before it executes
+	before []func(ctx context.Context, name string)
+
+	// after are called on each action's goroutine after it executes
+	after []func(ctx context.Context, name string, err error)
+
+	// until is the action whose finishing stops the resolve, if not empty
+	until string
+
+	// stopped is set once until has finished, after which no action begins
+	stopped *int32
+
+	// stopping is whether executing actions are cancelled once until has finished
+	stopping bool
+
+	// pause holds actions back from starting while the resolve is paused
+	pause *pauseGate
+
+	// begun is when the resolve began
+	begun time.Time
+
+	// ended is when the resolve finished, set before finished is closed
+	ended *time.Time
+}
+
+// plannedLaunch is an action to be launched once the dfs is complete
+type plannedLaunch struct {
+	name   string
+	action ActionE
+	wg     *sync.WaitGroup
+}
+
+// parkedAction is an action without a goroutine whose dependencies are not yet satisfied
+type parkedAction struct {
+	action ActionE
+
+	// pending is the number of dependencies that have not exited
+	pending int32
+
+	// launched is set to 1 once the goroutine has been launched
+	launched int32
+}
+
+// containsAny returns if any of names are in set
+func containsAny(set *SyncStringSet, names StringSet) bool {
+	for name := range names {
+		if set.Contains(name) {
+			return true
+		}
+	}
+	return false
 }
 
 // visitComplete is an action to be performed after an action's goroutine has ended
-func (s *search) visitComplete(name string, parents StringSet) {
-	s.wg.Done()
+// dependencies returns the dependencies of the action name, which for
+// an action added by Node.AddDependent is the action that added it
+func (s *search) dependencies(g *Graph, name string) StringSet {
+	if parent, ok := s.added.parent(name); ok {
+		return StringSet{parent: struct{}{}}
+	}
+	return g.treeOrder[name]
+}
+
+func (s *search) visitComplete(name string, parents StringSet, node *Node) {
+	s.completed.Add(name)
+	// The resolve is done once every action's wg is done, so wg is done
+	// last: the search may be reused once the resolve is done
+	defer s.wg.Done()
 	for parent := range parents {
 		parentWg := s.waits[parent]
 		if parentWg != nil {
 			parentWg.Done()
 		}
+		if parked := s.parked[parent]; parked != nil && atomic.AddInt32(&parked.pending, -1) == 0 {
+			node.g.unpark(*s, parent, parked, node.recorder)
+		}
+	}
+	for _, dependentWg := range node.seal() {
+		dependentWg.Done()
+	}
+}
+
+// outcome holds the first error that ended a resolve
+type outcome struct {
+	mx  *sync.Mutex
+	err error
+}
+
+// set records err unless an earlier error was recorded
+func (o *outcome) set(err error) {
+	if err == nil {
+		return
+	}
+	o.mx.Lock()
+	if o.err == nil {
+		o.err = err
+	}
+	o.mx.Unlock()
+}
+
+// get returns the recorded error
+func (o *outcome) get() error {
+	o.mx.Lock()
+	defer o.mx.Unlock()
+	return o.err
+}
+
+// begin marks name as started unless the context for this search is done.
+// It is serialized with abort, so once abort returns no further
+// actions will begin and the started set is final.
+func (s *search) begin(name string) bool {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	if s.searchContextDone() || s.isStopped() {
+		return false
+	}
+	s.started.Add(name)
+	return true
+}
+
+// allow returns if every gate allows name to execute
+func (s *search) allow(name string) bool {
+	for _, gate := range s.gates {
+		if !gate.Allow(name) {
+			return false
+		}
 	}
+	return true
+}
+
+// action returns the action to execute for name, which is its override if it has one
+func (s *search) action(g *Graph, name string) ActionE {
+	if override, ok := s.overrides[name]; ok {
+		return override
+	}
+	return g.actions[name]
+}
+
+// stop prevents any further action from beginning,
+// cancelling those that are executing if stopping
+func (s *search) stop() {
+	s.mx.Lock()
+	atomic.StoreInt32(s.stopped, 1)
+	s.mx.Unlock()
+	if s.stopping {
+		s.nodes.cancelAll()
+	}
+}
+
+// isStopped returns if no further action may begin
+func (s *search) isStopped() bool {
+	return atomic.LoadInt32(s.stopped) == 1
+}
+
+// abort cancels the context for this search
+func (s *search) abort() {
+	s.cancel(nil)
+}
+
+// cancel cancels the context for this search with cause,
+// or with context.Canceled if cause is nil
+func (s *search) cancel(cause error) {
+	s.mx.Lock()
+	s.done(cause)
+	s.mx.Unlock()
+}
+
+// each calls fn for every name in names, stopping at the first error.
+// Names are visited in the order they were added to the graph if the search
+// is ranked, or in sorted order if the search is sorted.
+func (s *search) each(names StringSet, fn func(name string) error) error {
+	if s.rank != nil || s.sorted || s.shuffle != nil {
+		var ordered []string
+		if s.rank != nil {
+			ordered = rankedNames(names, s.rank)
+		} else {
+			ordered = sortedNames(names)
+		}
+		if s.shuffle != nil {
+			s.shuffle.Shuffle(len(ordered), func(i, j int) {
+				ordered[i], ordered[j] = ordered[j], ordered[i]
+			})
+		}
+		for _, name := range ordered {
+			if err := fn(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for name := range names {
+		if err := fn(name); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // searchContextDone returns if the context for this search is done
@@ -228,9 +1512,14 @@ func (s *search) searchContextDone() bool {
 }
 
 // createWaitGroupForDependents creates a wait group for a name that
-// will wait for each dependent
+// will wait for each dependent. It may only be called by the dfs: see search.waits.
 func (s *search) createWaitGroupForDependents(name string, numDependents int) *sync.WaitGroup {
-	wg := &sync.WaitGroup{}
+	var wg *sync.WaitGroup
+	if s.scratch != nil {
+		wg = s.scratch.waitGroup()
+	} else {
+		wg = &sync.WaitGroup{}
+	}
 	wg.Add(numDependents)
 	s.waits[name] = wg
 	return wg
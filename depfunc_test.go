@@ -5,6 +5,8 @@ import (
 
 	"context"
 
+	"runtime"
+
 	"strings"
 
 	"time"
@@ -13,6 +15,7 @@ import (
 
 	"sync/atomic"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -49,8 +52,45 @@ func visitorAction(name string) Action {
 
 func sampleaction(ctx context.Context, arg interface{}) {}
 
+func failingAction(name string) ActionE {
+	return func(ctx context.Context, arg interface{}) error {
+		arg.(*visitordata).Visit(name)
+		return errors.New("failed " + name)
+	}
+}
+
+type errorRecorder struct {
+	*noopVisitRecorder
+	mx        *sync.Mutex
+	errors    map[string]error
+	swallowed map[string]error
+}
+
+func newErrorRecorder() *errorRecorder {
+	return &errorRecorder{
+		mx:        &sync.Mutex{},
+		errors:    make(map[string]error),
+		swallowed: make(map[string]error),
+	}
+}
+
+func (r *errorRecorder) Error(name string, err error) {
+	r.mx.Lock()
+	r.errors[name] = err
+	r.mx.Unlock()
+}
+
+func (r *errorRecorder) Swallow(name string, err error) {
+	r.mx.Lock()
+	r.swallowed[name] = err
+	r.mx.Unlock()
+}
+
+// testContext returns a context that times out after testTimeout. Its cancel function is
+// called by a timer rather than discarded, which go vet reports as a leaked context.
 func testContext() context.Context {
-	ctx, _ := context.WithTimeout(context.Background(), testTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	time.AfterFunc(testTimeout, cancel)
 	return ctx
 }
 
@@ -69,6 +109,58 @@ func TestGraph_AddAction(t *testing.T) {
 	assert.Len(t, g.actions, 1)
 }
 
+func TestSimple(t *testing.T) {
+	g := NewGraph()
+	called := false
+	var got interface{}
+	g.AddAction("a", Simple(func() { called = true }))
+	g.AddAction("b", SimpleArg(func(arg interface{}) { got = arg }))
+	g.LinkDependency("a", "b")
+
+	ctx, err := g.Resolve(testContext(), "arg")
+	<-ctx.Done()
+
+	assert.NoError(t, err)
+	assert.True(t, called)
+	assert.Equal(t, "arg", got)
+}
+
+func TestGraph_AddSuperRoot(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", visitorAction("a"))
+	g.AddAction("b", visitorAction("b"))
+	g.LinkDependency("a", "b")
+
+	assert.NoError(t, g.AddSuperRoot("done", visitorAction("done")))
+	assert.Equal(t, [][2]string{{"a", "b"}, {"b", "done"}}, g.Edges())
+
+	g.AddAction("c", visitorAction("c"))
+	g.AddAction("d", visitorAction("d"))
+	g.LinkDependency("c", "d")
+	assert.Equal(t, [][2]string{{"a", "b"}, {"b", "done"}, {"c", "d"}, {"d", "done"}}, g.Edges())
+
+	visitorData := newVisitordata()
+	ctx, err := g.Resolve(testContext(), visitorData)
+	<-ctx.Done()
+
+	assert.NoError(t, err)
+	assert.Len(t, visitorData.visited, 5)
+	assert.Equal(t, "done", visitorData.visited[4])
+}
+
+func TestGraph_AddSuperRoot_errors(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", sampleaction)
+
+	assert.EqualError(t, g.AddSuperRoot("a", sampleaction), `action "a" already exists`)
+	assert.NoError(t, g.AddSuperRoot("done", sampleaction))
+	assert.EqualError(t, g.AddSuperRoot("finally", sampleaction), `graph already has super root "done"`)
+	err := g.LinkDependency("done", "a")
+	assert.EqualError(t, err, `super root cannot have dependents: "done"`)
+	assert.Equal(t, ErrSuperRootDependent, errors.Cause(err))
+	assert.NoError(t, g.Validate())
+}
+
 func TestGraph_AddAction_noName(t *testing.T) {
 	g := NewGraph()
 
@@ -77,6 +169,227 @@ func TestGraph_AddAction_noName(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestGraph_AddActionE(t *testing.T) {
+	g := NewGraph()
+
+	err := g.AddActionE("action", failingAction("action"))
+
+	assert.NoError(t, err)
+	assert.Len(t, g.actions, 1)
+	assert.False(t, g.continueOnError.Contains("action"))
+}
+
+func TestGraph_AddActions(t *testing.T) {
+	g := NewGraph()
+
+	err := g.AddActions(map[string]Action{
+		"a": sampleaction,
+		"b": sampleaction,
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, g.actions, 2)
+}
+
+func TestGraph_AddActions_noName(t *testing.T) {
+	g := NewGraph()
+
+	err := g.AddActions(map[string]Action{
+		"a": sampleaction,
+		"":  sampleaction,
+	})
+
+	assert.EqualError(t, err, "name must not be empty")
+	assert.Len(t, g.actions, 0)
+}
+
+func TestGraph_AddActions_nilAction(t *testing.T) {
+	g := NewGraph()
+
+	err := g.AddActions(map[string]Action{
+		"a": sampleaction,
+		"b": nil,
+	})
+
+	assert.EqualError(t, err, `action "b": action must not be nil`)
+	assert.True(t, errors.Is(err, ErrNilAction))
+	assert.Len(t, g.actions, 0)
+}
+
+func TestGraph_Add_nilAction(t *testing.T) {
+	g := NewGraph()
+
+	for name, err := range map[string]error{
+		"AddAction":                g.AddAction("a", nil),
+		"AddActionE":               g.AddActionE("a", nil),
+		"AddActionContinueOnError": g.AddActionContinueOnError("a", nil),
+		"AddActionWithTags":        g.AddActionWithTags("a", nil, "tag"),
+		"AddActionWithCost":        g.AddActionWithCost("a", nil, time.Second),
+		"AddAsyncAction":           g.AddAsyncAction("a", nil),
+		"AddResultAction":          g.AddResultAction("a", nil),
+		"AddDataflowAction":        g.AddDataflowAction("a", nil),
+		"AddSuperRoot":             g.AddSuperRoot("a", nil),
+	} {
+		assert.Equal(t, ErrNilAction, err, name)
+	}
+	assert.Len(t, g.actions, 0)
+	assert.Empty(t, g.superRoot)
+	assert.Empty(t, g.continueOnError)
+}
+
+func TestGraph_Add_noNameNilAction(t *testing.T) {
+	g := NewGraph()
+
+	for name, err := range map[string]error{
+		"AddAction":                g.AddAction("", nil),
+		"AddActionE":               g.AddActionE("", nil),
+		"AddActionContinueOnError": g.AddActionContinueOnError("", nil),
+		"AddActionWithTags":        g.AddActionWithTags("", nil, "tag"),
+		"AddActionWithCost":        g.AddActionWithCost("", nil, time.Second),
+		"AddAsyncAction":           g.AddAsyncAction("", nil),
+		"AddResultAction":          g.AddResultAction("", nil),
+		"AddDataflowAction":        g.AddDataflowAction("", nil),
+		"AddSuperRoot":             g.AddSuperRoot("", nil),
+	} {
+		assert.Equal(t, ErrEmptyName, err, name)
+	}
+	assert.Len(t, g.actions, 0)
+}
+
+func TestGraph_AddActionContinueOnError(t *testing.T) {
+	g := NewGraph()
+
+	err := g.AddActionContinueOnError("action", failingAction("action"))
+
+	assert.NoError(t, err)
+	assert.Len(t, g.actions, 1)
+	assert.True(t, g.continueOnError.Contains("action"))
+}
+
+func TestGraph_AddActionContinueOnError_noName(t *testing.T) {
+	g := NewGraph()
+
+	err := g.AddActionContinueOnError("", failingAction("action"))
+
+	assert.Error(t, err)
+	assert.Len(t, g.continueOnError, 0)
+}
+
+func TestGraph_AddActionWithTags(t *testing.T) {
+	g := NewGraph()
+
+	err := g.AddActionWithTags("action", sampleaction, "qa", "cheap")
+
+	assert.NoError(t, err)
+	assert.Len(t, g.actions, 1)
+	assert.Len(t, g.tags["action"], 2)
+}
+
+func TestGraph_AddActionWithTags_noName(t *testing.T) {
+	g := NewGraph()
+
+	err := g.AddActionWithTags("", sampleaction, "qa")
+
+	assert.Error(t, err)
+	assert.Len(t, g.tags, 0)
+}
+
+func TestGraph_AddAction_replacesTags(t *testing.T) {
+	g := NewGraph()
+	g.AddActionWithTags("action", sampleaction, "qa")
+
+	g.AddAction("action", sampleaction)
+
+	assert.Len(t, g.ActionsByTag("qa"), 0)
+}
+
+func TestGraph_ActionsByTag(t *testing.T) {
+	g := NewGraph()
+	g.AddActionWithTags("c", sampleaction, "qa")
+	g.AddActionWithTags("a", sampleaction, "qa", "input")
+	g.AddActionWithTags("b", sampleaction, "input")
+	g.AddAction("d", sampleaction)
+
+	assert.Equal(t, []string{"a", "c"}, g.ActionsByTag("qa"))
+	assert.Equal(t, []string{"a", "b"}, g.ActionsByTag("input"))
+	assert.Len(t, g.ActionsByTag("missing"), 0)
+}
+
+func TestGraph_AddAsyncAction(t *testing.T) {
+	g := NewGraph()
+
+	err := g.AddAsyncAction("action", func(ctx context.Context, arg interface{}) context.Context {
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, g.actions, 1)
+}
+
+func TestGraph_AddAsyncAction_noName(t *testing.T) {
+	g := NewGraph()
+
+	err := g.AddAsyncAction("", func(ctx context.Context, arg interface{}) context.Context {
+		return nil
+	})
+
+	assert.Error(t, err)
+}
+
+func tracingMiddleware(label string) Middleware {
+	return func(name string, next Action) Action {
+		return func(ctx context.Context, arg interface{}) {
+			arg.(*visitordata).Visit(label + ">" + name)
+			next(ctx, arg)
+			arg.(*visitordata).Visit(label + "<" + name)
+		}
+	}
+}
+
+func TestGraph_Use(t *testing.T) {
+	g := NewGraph()
+	g.Use(tracingMiddleware("outer"))
+	g.AddAction("a", visitorAction("a"))
+	g.Use(tracingMiddleware("inner"))
+
+	visitorData := newVisitordata()
+	ctx, err := g.Resolve(testContext(), visitorData)
+	<-ctx.Done()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"outer>a", "inner>a", "a", "inner<a", "outer<a"}, visitorData.visited)
+}
+
+func TestGraph_Use_error(t *testing.T) {
+	g := NewGraph()
+	g.Use(tracingMiddleware("mw"))
+	g.AddActionE("a", failingAction("a"))
+	recorder := newErrorRecorder()
+
+	ctx, err := g.Resolve(testContext(), newVisitordata(), recorder)
+	<-ctx.Done()
+
+	assert.NoError(t, err)
+	assert.EqualError(t, recorder.errors["a"], "failed a")
+}
+
+func TestGraph_Use_shortCircuit(t *testing.T) {
+	g := NewGraph()
+	g.Use(func(name string, next Action) Action {
+		return func(ctx context.Context, arg interface{}) {}
+	})
+	g.AddActionE("a", failingAction("a"))
+	recorder := newErrorRecorder()
+	visitorData := newVisitordata()
+
+	ctx, err := g.Resolve(testContext(), visitorData, recorder)
+	<-ctx.Done()
+
+	assert.NoError(t, err)
+	assert.Len(t, visitorData.visited, 0)
+	assert.Len(t, recorder.errors, 0)
+}
+
 func TestGraph_LinkDependency(t *testing.T) {
 	g := NewGraph()
 	g.AddAction("a", sampleaction)
@@ -96,7 +409,8 @@ func TestGraph_LinkDependency_noName(t *testing.T) {
 
 	err := g.LinkDependency("a", "")
 
-	assert.Error(t, err)
+	assert.EqualError(t, err, "name must not be empty")
+	assert.True(t, errors.Is(err, ErrEmptyName))
 }
 
 func TestGraph_LinkDependency_noActionForName(t *testing.T) {
@@ -105,7 +419,12 @@ func TestGraph_LinkDependency_noActionForName(t *testing.T) {
 
 	err := g.LinkDependency("a", "b")
 
-	assert.Error(t, err)
+	assert.EqualError(t, err, `action not added: "b"`)
+	assert.True(t, errors.Is(err, ErrUnknownAction))
+	var nameErr *NameError
+	if assert.True(t, errors.As(err, &nameErr)) {
+		assert.Equal(t, "b", nameErr.Name)
+	}
 }
 
 func TestGraph_LinkDependency_noParentName(t *testing.T) {
@@ -115,31 +434,323 @@ func TestGraph_LinkDependency_noParentName(t *testing.T) {
 
 	err := g.LinkDependency("", "b")
 
-	assert.Error(t, err)
+	assert.EqualError(t, err, "parent: name must not be empty")
+	assert.True(t, errors.Is(err, ErrEmptyName))
+}
+
+func TestGraph_LinkDependency_noActionForParentName(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("b", sampleaction)
+
+	err := g.LinkDependency("a", "b")
+
+	assert.EqualError(t, err, `parent action not added: "a"`)
+	assert.Equal(t, ErrUnknownParent, errors.Cause(err))
+	var nameErr *NameError
+	if assert.True(t, errors.As(err, &nameErr)) {
+		assert.Equal(t, "a", nameErr.Name)
+	}
+}
+
+func TestGraph_LinkDependencyStrict(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", sampleaction)
+	g.AddAction("b", sampleaction)
+
+	err := g.LinkDependencyStrict("a", "b")
+
+	assert.NoError(t, err)
+	assert.True(t, g.treeOrder["b"].Contains("a"))
+}
+
+func TestGraph_LinkDependencyStrict_duplicate(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", sampleaction)
+	g.AddAction("b", sampleaction)
+	g.LinkDependency("a", "b")
+
+	err := g.LinkDependencyStrict("a", "b")
+
+	assert.EqualError(t, err, `linking a->b: dependency already exists: "a"`)
+	assert.Equal(t, ErrDuplicateLink, errors.Cause(err))
+	var nameErr *NameError
+	if assert.True(t, errors.As(err, &nameErr)) {
+		assert.Equal(t, "a", nameErr.Name)
+	}
+}
+
+func TestGraph_LinkDependencyStrict_reverse(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", sampleaction)
+	g.AddAction("b", sampleaction)
+	g.LinkDependency("a", "b")
+
+	err := g.LinkDependencyStrict("b", "a")
+
+	assert.NoError(t, err)
+}
+
+func TestGraph_LinkDependencyStrict_noActionForName(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", sampleaction)
+
+	err := g.LinkDependencyStrict("a", "b")
+
+	assert.Error(t, err)
+}
+
+func TestGraph_LinkDependencyChecked(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", sampleaction)
+	g.AddAction("b", sampleaction)
+	g.AddAction("c", sampleaction)
+	g.LinkDependency("a", "b")
+
+	err := g.LinkDependencyChecked("b", "c")
+
+	assert.NoError(t, err)
+	assert.True(t, g.treeOrder["c"].Contains("b"))
+}
+
+func TestGraph_LinkDependencyChecked_cycle(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", sampleaction)
+	g.AddAction("b", sampleaction)
+	g.AddAction("c", sampleaction)
+	g.LinkDependency("a", "b")
+	g.LinkDependency("b", "c")
+
+	err := g.LinkDependencyChecked("c", "a")
+
+	assert.EqualError(t, err, `linking c->a: dependency would create a cycle: "c"`)
+	assert.Equal(t, ErrWouldCycle, errors.Cause(err))
+	var nameErr *NameError
+	if assert.True(t, errors.As(err, &nameErr)) {
+		assert.Equal(t, "c", nameErr.Name)
+	}
+	assert.False(t, g.treeOrder["a"].Contains("c"))
+}
+
+func TestGraph_LinkDependencyChecked_self(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", sampleaction)
+
+	err := g.LinkDependencyChecked("a", "a")
+
+	assert.EqualError(t, err, `linking a->a: dependency would create a cycle: "a"`)
+	assert.Equal(t, ErrWouldCycle, errors.Cause(err))
+}
+
+func TestGraph_LinkDependencyChecked_noActionForName(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", sampleaction)
+
+	err := g.LinkDependencyChecked("a", "b")
+
+	assert.EqualError(t, err, `action not added: "b"`)
+}
+
+func TestGraph_Resolve(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", visitorAction("a"))
+	g.AddAction("b", visitorAction("b"))
+	g.LinkDependency("a", "b")
+
+	visitorData := newVisitordata()
+
+	ctx, err := g.Resolve(testContext(), visitorData)
+	<-ctx.Done()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, visitorData.visited)
+	assert.Equal(t, context.Canceled, context.Cause(ctx))
+}
+
+func TestGraph_Resolve_actionError(t *testing.T) {
+	g := NewGraph()
+	g.AddActionE("a", failingAction("a"))
+	g.AddAction("b", visitorAction("b"))
+	g.LinkDependency("a", "b")
+
+	visitorData := newVisitordata()
+	recorder := newErrorRecorder()
+
+	ctx, err := g.Resolve(testContext(), visitorData, recorder)
+	<-ctx.Done()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a"}, visitorData.visited)
+	assert.EqualError(t, recorder.errors["a"], "failed a")
+	assert.Len(t, recorder.swallowed, 0)
+	assert.EqualError(t, context.Cause(ctx), `action "a": failed a`)
+}
+
+func TestGraph_Resolve_panic(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", func(ctx context.Context, arg interface{}) {
+		panic("boom")
+	})
+	g.AddAction("b", visitorAction("b"))
+	g.LinkDependency("a", "b")
+
+	visitorData := newVisitordata()
+	log, recorder := NewEventLog()
+	stats := NewStatistics()
+
+	r, err := g.Start(testContext(), visitorData, WithRecorders(recorder, stats.Recorder()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Wait()
+
+	assert.Equal(t, ErrPanic, errors.Cause(err))
+	assert.EqualError(t, err, `action "a": boom: action panicked`)
+	assert.Len(t, visitorData.visited, 0)
+	if i := log.Index("a", EventPanic); assert.NotEqual(t, -1, i) {
+		assert.Equal(t, "boom", log.Events()[i].Value)
+		assert.True(t, i < log.Index("a", EventError))
+	}
+	assert.Equal(t, stringSet("a"), stats.Panicked())
+	assert.Equal(t, 1, stats.Summary().Panicked)
+}
+
+func TestGraph_Resolve_panicContinueOnError(t *testing.T) {
+	g := NewGraph()
+	g.AddActionContinueOnError("a", func(ctx context.Context, arg interface{}) error {
+		panic("boom")
+	})
+	g.AddAction("b", visitorAction("b"))
+	g.LinkDependency("a", "b")
+
+	visitorData := newVisitordata()
+	recorder := newErrorRecorder()
+
+	r, err := g.Start(testContext(), visitorData, WithRecorders(recorder))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, r.Wait())
+	assert.Equal(t, []string{"b"}, visitorData.visited)
+	assert.Equal(t, ErrPanic, errors.Cause(recorder.swallowed["a"]))
+}
+
+func TestGraph_Resolve_continueOnError(t *testing.T) {
+	g := NewGraph()
+	g.AddActionContinueOnError("a", failingAction("a"))
+	g.AddAction("b", visitorAction("b"))
+	g.LinkDependency("a", "b")
+
+	visitorData := newVisitordata()
+	recorder := newErrorRecorder()
+
+	ctx, err := g.Resolve(testContext(), visitorData, recorder, NewStatistics().Recorder())
+	<-ctx.Done()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, visitorData.visited)
+	assert.EqualError(t, recorder.swallowed["a"], "failed a")
+	assert.Len(t, recorder.errors, 0)
+}
+
+func TestGraph_Resolve_async(t *testing.T) {
+	g := NewGraph()
+	g.AddAsyncAction("a", func(ctx context.Context, arg interface{}) context.Context {
+		complete, done := context.WithCancel(context.Background())
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			arg.(*visitordata).Visit("a")
+			done()
+		}()
+		return complete
+	})
+	g.AddAction("b", visitorAction("b"))
+	g.LinkDependency("a", "b")
+
+	visitorData := newVisitordata()
+
+	ctx, err := g.Resolve(testContext(), visitorData)
+	<-ctx.Done()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, visitorData.visited)
+}
+
+func TestGraph_Resolve_asyncCancelled(t *testing.T) {
+	g := NewGraph()
+	g.AddAsyncAction("a", func(ctx context.Context, arg interface{}) context.Context {
+		return context.Background()
+	})
+	g.AddAction("b", visitorAction("b"))
+	g.LinkDependency("a", "b")
+
+	visitorData := newVisitordata()
+	resolveCtx, done := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer done()
+
+	ctx, err := g.Resolve(resolveCtx, visitorData)
+	<-ctx.Done()
+
+	assert.NoError(t, err)
+	assert.Len(t, visitorData.visited, 0)
+}
+
+func TestGraph_Start_asyncTimeout(t *testing.T) {
+	g := NewGraph()
+	g.AddAsyncAction("a", func(ctx context.Context, arg interface{}) context.Context {
+		return context.Background()
+	})
+	g.AddAction("b", visitorAction("b"))
+	g.LinkDependency("a", "b")
+
+	visitorData := newVisitordata()
+	r, err := g.Start(testContext(), visitorData, WithActionTimeout("a", time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.EqualError(t, r.Wait(), `action "a": context deadline exceeded`)
+	assert.Empty(t, visitorData.visited)
+	assert.Equal(t, StatusAborted, r.Status()["b"])
 }
 
-func TestGraph_LinkDependency_noActionForParentName(t *testing.T) {
+func TestGraph_Start_asyncReleasesScheduler(t *testing.T) {
+	release, done := context.WithCancel(context.Background())
 	g := NewGraph()
-	g.AddAction("b", sampleaction)
+	g.AddAsyncAction("a", func(ctx context.Context, arg interface{}) context.Context {
+		return release
+	})
+	// b only gets the Scheduler's one slot if a is not holding it while it is pending
+	g.AddAction("b", func(ctx context.Context, arg interface{}) {
+		done()
+	})
 
-	err := g.LinkDependency("a", "b")
+	r, err := g.Start(testContext(), nil, WithScheduler(NewScheduler(1)), WithLazyLaunch(), WithInsertionOrder())
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	assert.Error(t, err)
+	assert.NoError(t, r.Wait())
 }
 
-func TestGraph_Resolve(t *testing.T) {
+func TestGraph_ResolveArgs(t *testing.T) {
 	g := NewGraph()
 	g.AddAction("a", visitorAction("a"))
 	g.AddAction("b", visitorAction("b"))
 	g.LinkDependency("a", "b")
+	args := map[string]*visitordata{
+		"a": newVisitordata(),
+		"b": newVisitordata(),
+	}
 
-	visitorData := newVisitordata()
-
-	ctx, err := g.Resolve(testContext(), visitorData)
+	ctx, err := g.ResolveArgs(testContext(), func(name string) interface{} {
+		return args[name]
+	})
 	<-ctx.Done()
 
 	assert.NoError(t, err)
-	assert.Equal(t, []string{"a", "b"}, visitorData.visited)
+	assert.Equal(t, []string{"a"}, args["a"].visited)
+	assert.Equal(t, []string{"b"}, args["b"].visited)
 }
 
 func TestGraph_Resolve_contextDone(t *testing.T) {
@@ -160,6 +771,101 @@ func TestGraph_Resolve_contextDone(t *testing.T) {
 	<-ctx.Done()
 
 	assert.Len(t, visitorData.visited, 0)
+
+	r, err := g.Start(resolveCtx, visitorData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Wait()
+
+	assert.Equal(t, []string{"a", "b"}, r.NotStarted())
+}
+
+func TestGraph_Resolve_contextDone_setup(t *testing.T) {
+	g := deepGraph(t, 12)
+
+	resolveCtx, done := context.WithCancel(testContext())
+	done()
+
+	r, err := g.Start(resolveCtx, newVisitordata())
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-r.Done()
+
+	assert.Len(t, r.s.visited, 0)
+}
+
+func TestGraph_Resolve_cancelStress(t *testing.T) {
+	const iterations = 50
+	for i := 0; i < iterations; i++ {
+		g := deepGraph(t, 6)
+		resolveCtx, cancel := context.WithCancel(testContext())
+		cancelAt := IndexedName("n", 1+i*7%100)
+		g.AddAction(cancelAt, func(ctx context.Context, arg interface{}) {
+			arg.(*visitordata).Visit(cancelAt)
+			cancel()
+		})
+		failAt := IndexedName("n", 1+i*13%100)
+		if i%2 == 0 {
+			g.AddActionE(failAt, failingAction(failAt))
+		}
+		visitorData := newVisitordata()
+		stats := NewStatistics()
+
+		r, err := g.Start(resolveCtx, visitorData, WithRecorders(stats.Recorder()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		<-r.Done()
+		cancel()
+
+		executed := stringSet(visitorData.visited...)
+		assert.Equal(t, r.s.started.Copy(), executed)
+		for name := range r.s.visited {
+			assert.Contains(t, stats.enter, name)
+			assert.Contains(t, stats.exit, name)
+			_, started := stats.start[name]
+			_, finished := stats.finish[name]
+			assert.Equal(t, executed.Contains(name), started, name)
+			assert.Equal(t, started, finished, name)
+		}
+	}
+}
+
+func TestGraph_ResolveCancel(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", visitorAction("a"))
+	g.AddAction("b", visitorAction("b"))
+	g.LinkDependency("a", "b")
+	visitorData := newVisitordata()
+
+	ctx, cancel, err := g.ResolveCancel(testContext(), visitorData)
+	<-ctx.Done()
+	cancel()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, visitorData.visited)
+}
+
+func TestGraph_ResolveCancel_cancel(t *testing.T) {
+	release := make(chan struct{})
+	g := NewGraph()
+	g.AddAction("a", blockingAction(release))
+	g.AddAction("b", visitorAction("b"))
+	g.LinkDependency("a", "b")
+	visitorData := newVisitordata()
+	parent := testContext()
+
+	ctx, cancel, err := g.ResolveCancel(parent, visitorData)
+	cancel()
+	<-ctx.Done()
+	close(release)
+
+	assert.NoError(t, err)
+	assert.NoError(t, parent.Err())
+	assert.Len(t, visitorData.visited, 0)
+	assert.Equal(t, context.Canceled, context.Cause(ctx))
 }
 
 func TestGraph_Resolve_noRoots(t *testing.T) {
@@ -192,6 +898,174 @@ func TestGraph_Resolve_deepCycle(t *testing.T) {
 	<-ctx.Done()
 
 	assert.EqualError(t, err, "cycle detected")
+	assert.Equal(t, ErrCycle, context.Cause(ctx))
+}
+
+func TestGraph_Resolve_concurrentChange(t *testing.T) {
+	g := deepGraph(t, 4)
+	visitorData := newVisitordata()
+	r, err := g.Start(testContext(), visitorData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			name := IndexedName("added", i)
+			g.AddAction(name, visitorAction(name))
+			g.LinkDependency("n0", name)
+			g.Use(func(name string, next Action) Action { return next })
+		}
+	}()
+
+	assert.NoError(t, r.Wait())
+	<-done
+	for _, name := range visitorData.visited {
+		assert.False(t, strings.HasPrefix(name, "added"), name)
+	}
+
+	visitorData = newVisitordata()
+	r, err = g.Start(testContext(), visitorData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, r.Wait())
+	assert.Contains(t, visitorData.visited, "added99")
+}
+
+func TestGraph_ResolveWith_setupErrorCause(t *testing.T) {
+	noRoots := NewGraph()
+	noRoots.AddAction("a", visitorAction("a"))
+	noRoots.AddAction("b", visitorAction("b"))
+	noRoots.LinkDependency("a", "b")
+	noRoots.LinkDependency("b", "a")
+
+	cycle := NewGraph()
+	cycle.AddAction("a", visitorAction("a"))
+	cycle.AddAction("b", visitorAction("b"))
+	cycle.AddAction("c", visitorAction("c"))
+	cycle.AddAction("d", visitorAction("d"))
+	cycle.LinkDependency("a", "b")
+	cycle.LinkDependency("b", "c")
+	cycle.LinkDependency("c", "b")
+	cycle.LinkDependency("c", "d")
+
+	used := NewStatistics()
+	used.Recorder().(claimer).claim()
+
+	for _, tc := range []struct {
+		name string
+		g    *Graph
+		opts []ResolveOption
+		want string
+	}{
+		{name: "empty", g: NewGraph(), want: ErrNoRoots.Error()},
+		{name: "noRoots", g: noRoots, want: ErrNoRoots.Error()},
+		{name: "cycle", g: cycle, want: ErrCycle.Error()},
+		{name: "deadBranches", g: deadBranchGraph(), want: "dead branches can never run: b, c"},
+		{name: "statisticsInUse", g: definedGraph(t), opts: []ResolveOption{WithRecorders(used.Recorder())}, want: ErrStatisticsInUse.Error()},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, err := tc.g.ResolveWith(testContext(), newVisitordata(), tc.opts...)
+			<-ctx.Done()
+
+			assert.EqualError(t, err, tc.want)
+			assert.Equal(t, err, context.Cause(ctx))
+		})
+	}
+}
+
+// diamondGraph creates layers of width actions in which every action of a layer
+// depends on every action of the layer before it, named by IndexedName("n", layer*width+i)
+func diamondGraph(t Fataler, layers, width int) *Graph {
+	g := NewGraph()
+	for i := 0; i < layers*width; i++ {
+		name := IndexedName("n", i)
+		if err := g.AddAction(name, visitorAction(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for layer := 1; layer < layers; layer++ {
+		for i := 0; i < width; i++ {
+			for j := 0; j < width; j++ {
+				dependency := IndexedName("n", (layer-1)*width+j)
+				if err := g.LinkDependency(dependency, IndexedName("n", layer*width+i)); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}
+	return g
+}
+
+func TestGraph_Resolve_diamond(t *testing.T) {
+	const layers, width = 16, 16
+	g := diamondGraph(t, layers, width)
+
+	for _, opts := range [][]ResolveOption{nil, {EagerStart()}, {WithLazyLaunch()}} {
+		visitorData := newVisitordata()
+		r, err := g.Start(testContext(), visitorData, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.NoError(t, r.Wait())
+		assert.Len(t, visitorData.visited, layers*width)
+		position := make(map[string]int, len(visitorData.visited))
+		for i, name := range visitorData.visited {
+			position[name] = i
+		}
+		for _, edge := range g.Edges() {
+			assert.True(t, position[edge[0]] < position[edge[1]], "%s ran before %s", edge[1], edge[0])
+		}
+	}
+}
+
+// TestGraph_Resolve_diamondStress resolves a dense graph many times, concurrently, with every way of
+// launching actions, so that the race detector can check the dfs happens before any action reads waits
+func TestGraph_Resolve_diamondStress(t *testing.T) {
+	const layers, width, iterations = 8, 8, 20
+	g := diamondGraph(t, layers, width)
+	executor := func(task func()) { go task() }
+	scratch := NewScratch()
+
+	modes := map[string]func() []ResolveOption{
+		"default":  func() []ResolveOption { return nil },
+		"eager":    func() []ResolveOption { return []ResolveOption{EagerStart()} },
+		"lazy":     func() []ResolveOption { return []ResolveOption{WithLazyLaunch()} },
+		"executor": func() []ResolveOption { return []ResolveOption{WithExecutor(executor)} },
+		"scratch":  func() []ResolveOption { return []ResolveOption{WithScratch(scratch)} },
+	}
+	for mode, opts := range modes {
+		opts := opts
+		t.Run(mode, func(t *testing.T) {
+			t.Parallel()
+			for i := 0; i < iterations; i++ {
+				visitorData := newVisitordata()
+				r, err := g.Start(testContext(), visitorData, opts()...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				assert.NoError(t, r.Wait())
+				assert.Len(t, visitorData.visited, layers*width)
+			}
+		})
+	}
+}
+
+func TestGraph_Resolve_diamondCycle(t *testing.T) {
+	const layers, width = 16, 16
+	g := diamondGraph(t, layers, width)
+	g.LinkDependency(IndexedName("n", layers*width-1), IndexedName("n", width/2))
+	visitorData := newVisitordata()
+
+	ctx, err := g.ResolveWith(testContext(), visitorData)
+	<-ctx.Done()
+
+	assert.Equal(t, ErrCycle, err)
+	assert.Empty(t, visitorData.visited)
 }
 
 func definedGraph(t Fataler) *Graph {
@@ -243,7 +1117,7 @@ func definedGraph(t Fataler) *Graph {
 }
 
 func deepGraph(t Fataler, depth int) *Graph {
-	root := "0"
+	root := IndexedName("n", 0)
 	g := NewGraph()
 	g.AddAction(root, visitorAction(root))
 	index := int64(0)
@@ -264,21 +1138,30 @@ func deepGraphHelper(t Fataler, root string, depth int, index *int64, g *Graph)
 	left := atomic.AddInt64(index, 1)
 	right := atomic.AddInt64(index, 1)
 
-	leftName := string(left)
+	leftName := IndexedName("n", int(left))
 	must(g.AddAction(leftName, visitorAction(leftName)))
 	must(g.LinkDependency(root, leftName))
 	deepGraphHelper(t, leftName, depth-1, index, g)
 
-	rightName := string(right)
+	rightName := IndexedName("n", int(right))
 	must(g.AddAction(rightName, visitorAction(rightName)))
 	must(g.LinkDependency(root, rightName))
 	deepGraphHelper(t, rightName, depth-1, index, g)
 }
 
+func TestDeepGraph_names(t *testing.T) {
+	g := deepGraph(t, 2)
+
+	assert.Len(t, g.actions, 7)
+	for i := 0; i < 7; i++ {
+		assert.Contains(t, g.actions, IndexedName("n", i))
+	}
+}
+
 func TestGraph_collectRoots(t *testing.T) {
 	g := definedGraph(t)
 
-	roots := g.collectRoots()
+	roots := g.collectRoots(testContext())
 
 	expected := make(StringSet)
 	for _, c := range "fgdjk" {
@@ -322,24 +1205,48 @@ func BenchmarkGraph_Resolve(b *testing.B) {
 	}
 }
 
+func BenchmarkGraph_Resolve_large(b *testing.B) {
+	g := deepGraph(b, 14)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		visitorData := newVisitordata()
+		ctx, _ := g.Resolve(context.Background(), visitorData)
+		<-ctx.Done()
+	}
+}
+
 func BenchmarkGraph_Resolve_recorded(b *testing.B) {
 	g := deepGraph(b, 10)
-	recorder := NewStatistics().Recorder()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		visitorData := newVisitordata()
+		recorder := NewStatistics().Recorder()
 		ctx, _ := g.Resolve(testContext(), visitorData, recorder)
 		<-ctx.Done()
 	}
 }
 
+func BenchmarkGraph_Resolve_recorded_reset(b *testing.B) {
+	g := deepGraph(b, 10)
+	stats := NewStatistics()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		stats.Reset()
+		visitorData := newVisitordata()
+		r, _ := g.Start(testContext(), visitorData, WithRecorders(stats.Recorder()))
+		r.Wait()
+	}
+}
+
 func BenchmarkGraph_Resolve_recorded_multiple(b *testing.B) {
 	g := deepGraph(b, 10)
-	recorderA := NewStatistics().Recorder()
-	recorderB := NewStatistics().Recorder()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		visitorData := newVisitordata()
+		recorderA := NewStatistics().Recorder()
+		recorderB := NewStatistics().Recorder()
 		ctx, _ := g.Resolve(testContext(), visitorData, recorderA, recorderB)
 		<-ctx.Done()
 	}
@@ -357,11 +1264,269 @@ func BenchmarkGraph_Resolve_done(b *testing.B) {
 	}
 }
 
+func BenchmarkGraph_Resolve_done_large(b *testing.B) {
+	g := deepGraph(b, 14)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		visitorData := newVisitordata()
+		resolveCtx, done := context.WithCancel(testContext())
+		done()
+		ctx, _ := g.Resolve(resolveCtx, visitorData)
+		<-ctx.Done()
+	}
+}
+
+func BenchmarkGraph_Resolve_done_huge(b *testing.B) {
+	g := deepGraph(b, 16)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		visitorData := newVisitordata()
+		resolveCtx, done := context.WithCancel(testContext())
+		done()
+		ctx, _ := g.Resolve(resolveCtx, visitorData)
+		<-ctx.Done()
+	}
+}
+
+// goroutineRecorder records the peak number of goroutines as actions start
+type goroutineRecorder struct {
+	peak int64
+}
+
+func (r *goroutineRecorder) Enter(name string) {}
+
+func (r *goroutineRecorder) Start(name string) {
+	n := int64(runtime.NumGoroutine())
+	for {
+		peak := atomic.LoadInt64(&r.peak)
+		if n <= peak || atomic.CompareAndSwapInt64(&r.peak, peak, n) {
+			return
+		}
+	}
+}
+
+func (r *goroutineRecorder) Finish(name string) {}
+
+func (r *goroutineRecorder) Exit(name string) {}
+
+func benchmarkGoroutinePeak(b *testing.B, opts ...ResolveOption) {
+	g := deepGraph(b, 16)
+	recorder := &goroutineRecorder{}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		visitorData := newVisitordata()
+		ctx, _ := g.ResolveWith(context.Background(), visitorData, append(opts, WithRecorders(recorder))...)
+		<-ctx.Done()
+	}
+	b.ReportMetric(float64(atomic.LoadInt64(&recorder.peak)), "goroutines")
+}
+
+// firstFinishRecorder records when the first action of a resolve finished
+type firstFinishRecorder struct {
+	noopVisitRecorder
+	first int64
+}
+
+func (r *firstFinishRecorder) Finish(name string) {
+	atomic.CompareAndSwapInt64(&r.first, 0, time.Now().UnixNano())
+}
+
+func benchmarkFirstFinish(b *testing.B, opts ...ResolveOption) {
+	g := deepGraph(b, 14)
+	var total time.Duration
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		recorder := &firstFinishRecorder{}
+		visitorData := newVisitordata()
+		start := time.Now()
+		ctx, _ := g.ResolveWith(context.Background(), visitorData, append(opts, WithRecorders(recorder))...)
+		<-ctx.Done()
+		total += time.Unix(0, atomic.LoadInt64(&recorder.first)).Sub(start)
+	}
+	b.ReportMetric(float64(total.Nanoseconds())/float64(b.N), "ns-to-first-finish")
+}
+
+func BenchmarkGraph_Resolve_firstFinish(b *testing.B) {
+	benchmarkFirstFinish(b)
+}
+
+func BenchmarkGraph_Resolve_firstFinish_eagerStart(b *testing.B) {
+	benchmarkFirstFinish(b, EagerStart())
+}
+
+func BenchmarkGraph_Resolve_goroutinePeak(b *testing.B) {
+	benchmarkGoroutinePeak(b)
+}
+
+func BenchmarkGraph_Resolve_goroutinePeak_lazyLaunch(b *testing.B) {
+	benchmarkGoroutinePeak(b, WithLazyLaunch())
+}
+
+func edgelessGraph(t Fataler, size int) *Graph {
+	g := NewGraph()
+	for i := 0; i < size; i++ {
+		name := IndexedName("n", i)
+		if err := g.AddAction(name, visitorAction(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return g
+}
+
+func TestGraph_Resolve_enterBeforeStart(t *testing.T) {
+	graphs := map[string]*Graph{
+		"deep":     deepGraph(t, 8),
+		"edgeless": edgelessGraph(t, 512),
+	}
+	modes := map[string][]ResolveOption{
+		"default": nil,
+		"lazy":    {WithLazyLaunch()},
+		"eager":   {EagerStart()},
+	}
+	for label, g := range graphs {
+		for mode, opts := range modes {
+			events, err := g.ResolveStreamWith(context.Background(), newVisitordata(), opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			started := false
+			for e := range events {
+				switch e.Kind {
+				case EventStart:
+					started = true
+				case EventEnter:
+					assert.False(t, started, "%s (%s): %s entered after an action started", label, mode, e.Name)
+				}
+			}
+		}
+	}
+}
+
+func TestGraph_ResolveWith_eagerStart(t *testing.T) {
+	g := definedGraph(t)
+	visitorData := newVisitordata()
+
+	r, err := g.Start(testContext(), visitorData, EagerStart())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, r.Wait())
+	visited := strings.Join(visitorData.visited, "")
+	assert.Len(t, visited, 11)
+	assertOccursBefore(t, 'a', "bcdhegifjk", visited)
+	assertOccursBefore(t, 'i', "jk", visited)
+}
+
+func TestGraph_ResolveWith_eagerStart_cycle(t *testing.T) {
+	g := deepGraph(t, 6)
+	g.LinkDependency(IndexedName("n", 1), IndexedName("n", 0))
+	visitorData := newVisitordata()
+
+	ctx, err := g.ResolveWith(testContext(), visitorData, EagerStart())
+	<-ctx.Done()
+
+	assert.Equal(t, ErrCycle, err)
+	assert.Empty(t, visitorData.visited)
+}
+
+func TestGraph_Resolve_edgeless(t *testing.T) {
+	g := edgelessGraph(t, 4)
+
+	events, err := g.ResolveStreamWith(testContext(), newVisitordata(), WithSkip(skipNames("n3")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := collectEvents(events)
+
+	for _, name := range []string{"n0", "n1", "n2"} {
+		for _, kind := range []EventKind{EventEnter, EventStart, EventFinish, EventExit} {
+			assert.NotEqual(t, -1, indexOfEvent(out, name, kind), "missing %s %s", name, kind)
+		}
+	}
+	assert.NotEqual(t, -1, indexOfEvent(out, "n3", EventSkip))
+	assert.Len(t, out, 13)
+}
+
+func TestGraph_Resolve_edgeless_done(t *testing.T) {
+	g := edgelessGraph(t, 4)
+	ctx, cancel := context.WithCancel(testContext())
+	cancel()
+
+	visitorData := newVisitordata()
+	resolveCtx, err := g.Resolve(ctx, visitorData)
+	<-resolveCtx.Done()
+
+	assert.NoError(t, err)
+	assert.Empty(t, visitorData.visited)
+}
+
+func TestGraph_Resolve_edgeless_empty(t *testing.T) {
+	_, err := NewGraph().Resolve(testContext(), newVisitordata())
+
+	assert.Equal(t, ErrNoRoots, err)
+}
+
+func BenchmarkGraph_Resolve_edgeless(b *testing.B) {
+	g := edgelessGraph(b, 10000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		visitorData := newVisitordata()
+		ctx, _ := g.Resolve(context.Background(), visitorData)
+		<-ctx.Done()
+	}
+}
+
+func TestGraph_collectRoots_done(t *testing.T) {
+	g := deepGraph(t, 12)
+	ctx, cancel := context.WithCancel(testContext())
+	cancel()
+
+	roots := g.collectRoots(ctx)
+
+	assert.True(t, len(roots) < 1024)
+}
+
 func BenchmarkGraph_collectRoots(b *testing.B) {
 	g := deepGraph(b, 10)
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		for range g.collectRoots() {
-		}
+		g.collectRoots(context.Background())
+	}
+}
+
+func TestGraph_Start_skipDependents(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", visitorAction("a"))
+	g.AddActionE("b", func(ctx context.Context, arg interface{}) error {
+		arg.(*visitordata).Visit("b")
+		return errors.Wrap(ErrSkipDependents, "cached")
+	})
+	g.AddAction("c", visitorAction("c"))
+	g.AddAction("d", visitorAction("d"))
+	g.AddAction("e", visitorAction("e"))
+	g.AddAction("f", visitorAction("f"))
+	g.LinkDependency("a", "b")
+	g.LinkDependency("a", "c")
+	g.LinkDependency("b", "d")
+	g.LinkDependency("c", "d")
+	g.LinkDependency("d", "e")
+	g.LinkDependency("c", "f")
+
+	visitorData := newVisitordata()
+	r, err := g.Start(testContext(), visitorData)
+	if err != nil {
+		t.Fatal(err)
 	}
+
+	assert.NoError(t, r.Wait())
+	assert.ElementsMatch(t, []string{"a", "b", "c", "f"}, visitorData.visited)
+	status := r.Status()
+	assert.Equal(t, StatusFinished, status["b"])
+	assert.Equal(t, StatusSkipped, status["d"])
+	assert.Equal(t, StatusSkipped, status["e"])
 }
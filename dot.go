@@ -0,0 +1,79 @@
+package depfunc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// WriteDOT writes this Graph to w in the DOT language of Graphviz, such as:
+//
+//	digraph depfunc {
+//		"apples";
+//		"applesauce";
+//		"apples" -> "applesauce" [label="before"];
+//	}
+//
+// Each edge points from an action to an action that depends on it.
+// Actions and edges are written in sorted order.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	return g.WriteDOTWithStats(w, nil)
+}
+
+// WriteDOTWithStats writes this Graph to w like WriteDOT, with each action colored by
+// its status in the resolve recorded by stats: green if it finished successfully, red
+// if it failed, panicked, was cut off after it started or was aborted because a
+// dependency failed, yellow if it started and has not finished, and gray if it never
+// started. If stats is nil, the actions are not colored.
+func (g *Graph) WriteDOTWithStats(w io.Writer, stats *Statistics) error {
+	var snapshot *StatsSnapshot
+	if stats != nil {
+		s := stats.Snapshot()
+		snapshot = &s
+	}
+
+	names := make(StringSet, len(g.actions))
+	for name := range g.actions {
+		names.Add(name)
+	}
+	sorted := sortedNames(names)
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("digraph depfunc {\n")
+	for _, name := range sorted {
+		if snapshot == nil {
+			fmt.Fprintf(buf, "\t%s;\n", strconv.Quote(name))
+			continue
+		}
+		fmt.Fprintf(buf, "\t%s [style=filled, fillcolor=%s];\n", strconv.Quote(name), snapshot.dotColor(name))
+	}
+	for _, name := range sorted {
+		for _, dependent := range sortedNames(g.graphOrder[name]) {
+			fmt.Fprintf(buf, "\t%s -> %s [label=\"before\"];\n", strconv.Quote(name), strconv.Quote(dependent))
+		}
+	}
+	buf.WriteString("}\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// dotColor returns the color of the Action name in a DOT graph
+func (s StatsSnapshot) dotColor(name string) string {
+	_, started := s.start[name]
+	_, finished := s.finish[name]
+	_, exited := s.exit[name]
+	switch {
+	case s.errs[name] != nil || s.panics.Contains(name) || s.aborts.Contains(name):
+		return "red"
+	case finished:
+		return "green"
+	case started && exited:
+		return "red"
+	case started:
+		return "yellow"
+	default:
+		return "gray"
+	}
+}
@@ -0,0 +1,93 @@
+package depfunc
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraph_WriteDOT(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("apples", sampleaction)
+	g.AddAction("sugars", sampleaction)
+	g.AddAction("applesauce", sampleaction)
+	g.LinkDependency("apples", "applesauce")
+	g.LinkDependency("sugars", "applesauce")
+	buf := &bytes.Buffer{}
+
+	err := g.WriteDOT(buf)
+
+	assert.NoError(t, err)
+	assert.Equal(t, `digraph depfunc {
+	"apples";
+	"applesauce";
+	"sugars";
+	"apples" -> "applesauce" [label="before"];
+	"sugars" -> "applesauce" [label="before"];
+}
+`, buf.String())
+}
+
+func TestGraph_WriteDOTWithStats(t *testing.T) {
+	g := NewGraph()
+	for _, name := range []string{"aborted", "finished", "pending", "running"} {
+		g.AddAction(name, sampleaction)
+	}
+	g.LinkDependency("finished", "running")
+	stats := NewStatistics()
+	stats.start["finished"], stats.finish["finished"], stats.exit["finished"] = at(0), at(1), at(1)
+	stats.start["aborted"], stats.exit["aborted"] = at(0), at(1)
+	stats.start["running"] = at(1)
+	stats.exit["pending"] = at(1)
+	buf := &bytes.Buffer{}
+
+	err := g.WriteDOTWithStats(buf, stats)
+
+	assert.NoError(t, err)
+	assert.Equal(t, `digraph depfunc {
+	"aborted" [style=filled, fillcolor=red];
+	"finished" [style=filled, fillcolor=green];
+	"pending" [style=filled, fillcolor=gray];
+	"running" [style=filled, fillcolor=yellow];
+	"finished" -> "running" [label="before"];
+}
+`, buf.String())
+}
+
+func TestGraph_WriteDOTWithStats_failed(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("ok", sampleaction)
+	g.AddActionE("slow", func(ctx context.Context, arg interface{}) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	g.AddAction("after", sampleaction)
+	g.LinkChain("ok", "slow", "after")
+	stats, err := g.ResolveWithStats(testContext(), newVisitordata(), WithActionTimeout("slow", time.Millisecond))
+	assert.Error(t, err)
+	buf := &bytes.Buffer{}
+
+	err = g.WriteDOTWithStats(buf, stats)
+
+	assert.NoError(t, err)
+	assert.Equal(t, `digraph depfunc {
+	"after" [style=filled, fillcolor=red];
+	"ok" [style=filled, fillcolor=green];
+	"slow" [style=filled, fillcolor=red];
+	"ok" -> "slow" [label="before"];
+	"slow" -> "after" [label="before"];
+}
+`, buf.String())
+}
+
+func TestGraph_WriteDOT_writeError(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", sampleaction)
+
+	err := g.WriteDOT(&failingWriter{})
+
+	assert.EqualError(t, err, "write 1 failed")
+}
@@ -0,0 +1,116 @@
+package depfunc
+
+import "sync"
+
+// EventLog is an in-memory log of the events of a resolve, in the order they were recorded.
+// It is useful for asserting the order in which Actions were resolved.
+type EventLog struct {
+	mx     *sync.Mutex
+	events []Event
+}
+
+// NewEventLog creates an EventLog and the Recorder that records to it
+func NewEventLog() (*EventLog, Recorder) {
+	log := &EventLog{mx: &sync.Mutex{}}
+	return log, &eventLogRecorder{log: log}
+}
+
+// Events returns a copy of the events recorded so far
+func (l *EventLog) Events() []Event {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	events := make([]Event, len(l.events))
+	copy(events, l.events)
+	return events
+}
+
+// Index returns the position of the first event of kind for the Action name, or -1 if there is none
+func (l *EventLog) Index(name string, kind EventKind) int {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	for i, e := range l.events {
+		if e.Name == name && e.Kind == kind {
+			return i
+		}
+	}
+	return -1
+}
+
+func (l *EventLog) append(e Event) {
+	l.mx.Lock()
+	l.events = append(l.events, e)
+	l.mx.Unlock()
+}
+
+// eventLogRecorder is a Recorder that appends each event to an EventLog
+type eventLogRecorder struct {
+	log *EventLog
+
+	// id is the ID of the resolve being recorded, if any
+	id string
+}
+
+// WithID returns a Recorder that appends to the same EventLog, tagging each event with id
+func (r *eventLogRecorder) WithID(id string) Recorder {
+	return &eventLogRecorder{log: r.log, id: id}
+}
+
+func (r *eventLogRecorder) record(name string, kind EventKind, err error) {
+	r.log.append(Event{Name: name, Kind: kind, Err: err, ID: r.id})
+}
+
+func (r *eventLogRecorder) Retry(name string, attempt int, err error) {
+	r.log.append(Event{Name: name, Kind: EventRetry, Err: err, Attempt: attempt, ID: r.id})
+}
+
+func (r *eventLogRecorder) GiveUp(name string, reason error) {
+	r.record(name, EventGiveUp, reason)
+}
+
+func (r *eventLogRecorder) Enter(name string) {
+	r.record(name, EventEnter, nil)
+}
+
+func (r *eventLogRecorder) Start(name string) {
+	r.record(name, EventStart, nil)
+}
+
+func (r *eventLogRecorder) Finish(name string) {
+	r.record(name, EventFinish, nil)
+}
+
+func (r *eventLogRecorder) Exit(name string) {
+	r.record(name, EventExit, nil)
+}
+
+func (r *eventLogRecorder) Error(name string, err error) {
+	r.record(name, EventError, err)
+}
+
+func (r *eventLogRecorder) Swallow(name string, err error) {
+	r.record(name, EventSwallow, err)
+}
+
+func (r *eventLogRecorder) Skip(name string) {
+	r.record(name, EventSkip, nil)
+}
+
+func (r *eventLogRecorder) Gate(name string) {
+	r.record(name, EventGate, nil)
+}
+
+func (r *eventLogRecorder) NoChange(name string) {
+	r.record(name, EventNoChange, nil)
+}
+
+func (r *eventLogRecorder) Timeout(name string) {
+	r.record(name, EventTimeout, nil)
+}
+
+func (r *eventLogRecorder) Panic(name string, v interface{}) {
+	r.log.append(Event{Name: name, Kind: EventPanic, Value: v, ID: r.id})
+}
+
+func (r *eventLogRecorder) Abort(name string) {
+	r.record(name, EventAbort, nil)
+}
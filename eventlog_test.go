@@ -0,0 +1,42 @@
+package depfunc
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventLog(t *testing.T) {
+	log, recorder := NewEventLog()
+	offline := errors.New("offline")
+
+	recorder.Enter("a")
+	recordSwallow(recorder, "a", offline)
+	recorder.(IdentifiedRecorder).WithID("second").Exit("a")
+
+	assert.Equal(t, []Event{
+		{Name: "a", Kind: EventEnter},
+		{Name: "a", Kind: EventSwallow, Err: offline},
+		{Name: "a", Kind: EventExit, ID: "second"},
+	}, log.Events())
+	assert.Equal(t, 1, log.Index("a", EventSwallow))
+	assert.Equal(t, -1, log.Index("b", EventEnter))
+}
+
+func TestEventLog_resolved(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("apples", visitorAction("apples"))
+	g.AddAction("applesauce", visitorAction("applesauce"))
+	g.LinkDependency("apples", "applesauce")
+	log, recorder := NewEventLog()
+
+	ctx, err := g.Resolve(testContext(), newVisitordata(), recorder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-ctx.Done()
+
+	assert.Len(t, log.Events(), 8)
+	assert.True(t, log.Index("apples", EventFinish) < log.Index("applesauce", EventStart))
+}
@@ -0,0 +1,715 @@
+package depfunc
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Orphans returns the sorted names of all actions that have
+// neither dependencies nor dependents.
+// An orphan still runs when the Graph is resolved, but it is often
+// an action that was added and never linked by mistake.
+func (g *Graph) Orphans() []string {
+	var orphans []string
+	for name := range g.actions {
+		if len(g.treeOrder[name]) == 0 && len(g.graphOrder[name]) == 0 {
+			orphans = append(orphans, name)
+		}
+	}
+	sort.Strings(orphans)
+	return orphans
+}
+
+// Edges returns every dependency in this Graph as a {dependency, dependent} pair,
+// sorted by dependency and then by dependent
+func (g *Graph) Edges() [][2]string {
+	dependencies := make([]string, 0, len(g.graphOrder))
+	for dependency := range g.graphOrder {
+		dependencies = append(dependencies, dependency)
+	}
+	sort.Strings(dependencies)
+
+	var edges [][2]string
+	for _, dependency := range dependencies {
+		for _, dependent := range sortedNames(g.graphOrder[dependency]) {
+			edges = append(edges, [2]string{dependency, dependent})
+		}
+	}
+	return edges
+}
+
+// EdgeCount returns the number of dependencies in this Graph
+func (g *Graph) EdgeCount() int {
+	count := 0
+	for _, dependents := range g.graphOrder {
+		count += len(dependents)
+	}
+	return count
+}
+
+// FanOutStats summarizes how many dependents the actions of a Graph have
+type FanOutStats struct {
+	// Max is the most dependents any action has
+	Max int
+
+	// MaxAction is the name of an action with the most dependents, the least by name
+	MaxAction string
+
+	// Mean is the average number of dependents of an action
+	Mean float64
+}
+
+// FanOut summarizes how many dependents the actions of this Graph have
+func (g *Graph) FanOut() FanOutStats {
+	var stats FanOutStats
+	if len(g.actions) == 0 {
+		return stats
+	}
+	for name := range g.actions {
+		n := len(g.graphOrder[name])
+		if n > stats.Max || (n == stats.Max && (stats.MaxAction == "" || name < stats.MaxAction)) {
+			stats.Max, stats.MaxAction = n, name
+		}
+	}
+	stats.Mean = float64(g.EdgeCount()) / float64(len(g.actions))
+	return stats
+}
+
+// Validate checks that this Graph can be resolved.
+// It returns an error naming any linked actions that were never added,
+// ErrNoRoots if every action has a dependent, an error naming the
+// DeadBranches if there are any, or ErrCycle if the dependencies
+// anywhere in the Graph form a cycle.
+func (g *Graph) Validate() error {
+	if unknown := g.unknownLinks(); len(unknown) > 0 {
+		return errors.Errorf("links reference unknown actions: %s", strings.Join(sortedNames(unknown), ", "))
+	}
+	if !g.hasRoot() {
+		return ErrNoRoots
+	}
+	if dead := g.DeadBranches(); len(dead) > 0 {
+		return deadBranchesError(dead)
+	}
+	if g.hasCycle() {
+		return ErrCycle
+	}
+	return nil
+}
+
+// RunnableCount returns how many actions a resolve configured by opts launches,
+// each on a goroutine of its own unless WithExecutor is used. These are the
+// actions reachable from the roots, less those skipped by WithSkip or
+// WithTagFilter and those finished in the prior resolve of WithResume. Actions
+// added while resolving by Node.AddDependent are not counted.
+func (g *Graph) RunnableCount(opts ...ResolveOption) int {
+	cfg := newResolveConfig(opts)
+	reached := make(StringSet, len(g.actions))
+	var pending []string
+	for name := range g.actions {
+		if len(g.graphOrder[name]) == 0 && !cfg.resumed.Contains(name) {
+			reached.Add(name)
+			pending = append(pending, name)
+		}
+	}
+	for len(pending) > 0 {
+		next := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		for dep := range g.treeOrder[next] {
+			if !reached.Contains(dep) && !cfg.resumed.Contains(dep) {
+				reached.Add(dep)
+				pending = append(pending, dep)
+			}
+		}
+	}
+
+	skipped := g.skipped(cfg)
+	count := 0
+	for name := range reached {
+		if !skipped.Contains(name) {
+			count++
+		}
+	}
+	return count
+}
+
+// LintWarning is a suspicious pattern in a Graph found by Lint
+type LintWarning struct {
+	// Message describes the pattern and what it may mean
+	Message string
+
+	// Edges are the {dependency, dependent} pairs that are likely reversed, if any
+	Edges [][2]string
+}
+
+func (w LintWarning) String() string {
+	return w.Message
+}
+
+// Lint looks for links that were likely made in the wrong direction, by
+// swapping the parent and name of LinkDependency, which produces a Graph
+// that resolves in the wrong order without any error. outputs names the
+// actions that are meant to produce the result of the Graph, if known.
+//
+// Lint is heuristic: its warnings are advice, and a Graph with warnings may
+// well be correct. It warns about:
+//
+//   - Each of outputs that has dependents. An output is expected to be resolved
+//     last, so its links to its dependents are reported as likely reversed.
+//   - A Graph that begins at a single action, one with no dependencies, and ends
+//     at three or more, ones with no dependents, while the single action is not
+//     one of outputs. Many inputs combining into one output is a far more common
+//     shape than the reverse, so the single action may be the output.
+func (g *Graph) Lint(outputs ...string) []LintWarning {
+	var warnings []LintWarning
+	declared := make(StringSet, len(outputs))
+	for _, output := range outputs {
+		declared.Add(output)
+	}
+
+	for _, output := range sortedNames(declared) {
+		dependents := sortedNames(g.graphOrder[output])
+		if len(dependents) == 0 {
+			continue
+		}
+		edges := make([][2]string, len(dependents))
+		for i, dependent := range dependents {
+			edges[i] = [2]string{output, dependent}
+		}
+		warnings = append(warnings, LintWarning{
+			Message: fmt.Sprintf("output %q is a dependency of %s: its links may be reversed", output, strings.Join(dependents, ", ")),
+			Edges:   edges,
+		})
+	}
+
+	var sources, sinks []string
+	for name := range g.actions {
+		if len(g.treeOrder[name]) == 0 {
+			sources = append(sources, name)
+		}
+		if len(g.graphOrder[name]) == 0 {
+			sinks = append(sinks, name)
+		}
+	}
+	if len(sources) == 1 && len(sinks) >= 3 && !declared.Contains(sources[0]) {
+		sort.Strings(sinks)
+		warnings = append(warnings, LintWarning{
+			Message: fmt.Sprintf("the graph begins at %q alone and ends at %s: if %q is its output, its links are reversed",
+				sources[0], strings.Join(sinks, ", "), sources[0]),
+		})
+	}
+	return warnings
+}
+
+// DeadBranches returns the sorted names of all actions that can never run
+// when this Graph is resolved. An action can run if it is a root, an action
+// with no dependents in graphOrder, or if it is reachable from a root through
+// treeOrder, as a dependency of a dependency and so on. Any other action is
+// part of a dead branch: every path from it through its dependents ends in a
+// cycle or at a dependent that was never added.
+func (g *Graph) DeadBranches() []string {
+	live := make(StringSet, len(g.actions))
+	var pending []string
+	for name := range g.actions {
+		if len(g.graphOrder[name]) == 0 {
+			live.Add(name)
+			pending = append(pending, name)
+		}
+	}
+	for len(pending) > 0 {
+		next := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		for dep := range g.treeOrder[next] {
+			if !live.Contains(dep) {
+				live.Add(dep)
+				pending = append(pending, dep)
+			}
+		}
+	}
+
+	var dead []string
+	for name := range g.actions {
+		if !live.Contains(name) {
+			dead = append(dead, name)
+		}
+	}
+	sort.Strings(dead)
+	return dead
+}
+
+// deadBranchesError is the error for the actions of dead branches
+func deadBranchesError(dead []string) error {
+	return errors.Errorf("dead branches can never run: %s", strings.Join(dead, ", "))
+}
+
+// unknownLinks collects every name in the adjacency lists that has no action
+func (g *Graph) unknownLinks() StringSet {
+	unknown := make(StringSet)
+	for _, m := range []stringmultimap{g.treeOrder, g.graphOrder} {
+		for key, values := range m {
+			if _, ok := g.actions[key]; !ok {
+				unknown.Add(key)
+			}
+			for value := range values {
+				if _, ok := g.actions[value]; !ok {
+					unknown.Add(value)
+				}
+			}
+		}
+	}
+	return unknown
+}
+
+// hasRoot returns if any action has no dependents
+func (g *Graph) hasRoot() bool {
+	for name := range g.actions {
+		if len(g.graphOrder[name]) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// hasCycle returns if the dependencies of any action form a cycle
+func (g *Graph) hasCycle() bool {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(g.actions))
+
+	var visit func(name string) bool
+	visit = func(name string) bool {
+		state[name] = visiting
+		for dep := range g.treeOrder[name] {
+			switch state[dep] {
+			case visiting:
+				return true
+			case unvisited:
+				if visit(dep) {
+					return true
+				}
+			}
+		}
+		state[name] = visited
+		return false
+	}
+
+	for name := range g.actions {
+		if state[name] == unvisited && visit(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Equal returns if this Graph and other have the same action names and the
+// same dependencies between them. Action functions are not comparable, so
+// the bodies of the actions are not compared.
+func (g *Graph) Equal(other *Graph) bool {
+	if other == nil || len(g.actions) != len(other.actions) {
+		return false
+	}
+	for name := range g.actions {
+		if _, ok := other.actions[name]; !ok {
+			return false
+		}
+		if !setsEqual(g.treeOrder[name], other.treeOrder[name]) {
+			return false
+		}
+	}
+	return true
+}
+
+// setsEqual returns if a and b contain the same members
+func setsEqual(a, b StringSet) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for s := range a {
+		if !b.Contains(s) {
+			return false
+		}
+	}
+	return true
+}
+
+// Levels groups the actions of this Graph by their longest distance from
+// an action with no dependencies, so that every action in level k depends
+// only on actions in levels less than k. Names within a level are sorted.
+// ErrCycle is returned if the dependencies form a cycle.
+func (g *Graph) Levels() ([][]string, error) {
+	levels := make(map[string]int, len(g.actions))
+	visiting := make(StringSet)
+
+	var level func(name string) (int, error)
+	level = func(name string) (int, error) {
+		if l, ok := levels[name]; ok {
+			return l, nil
+		}
+		if visiting.Contains(name) {
+			return 0, ErrCycle
+		}
+		visiting.Add(name)
+		l := 0
+		for dep := range g.treeOrder[name] {
+			depLevel, err := level(dep)
+			if err != nil {
+				return 0, err
+			}
+			if depLevel+1 > l {
+				l = depLevel + 1
+			}
+		}
+		visiting.Remove(name)
+		levels[name] = l
+		return l, nil
+	}
+
+	var grouped [][]string
+	for name := range g.actions {
+		l, err := level(name)
+		if err != nil {
+			return nil, err
+		}
+		for len(grouped) <= l {
+			grouped = append(grouped, nil)
+		}
+		grouped[l] = append(grouped[l], name)
+	}
+	for _, names := range grouped {
+		sort.Strings(names)
+	}
+	return grouped, nil
+}
+
+// WalkTopo calls fn with the name of each action of this Graph in topological
+// order, so that every action is walked after each action it depends on, and
+// stops at the first error fn returns, which is returned. The order of actions
+// that do not depend on each other is unspecified. Unlike Levels, nothing is
+// collected, so WalkTopo suits streaming a large Graph. ErrCycle is returned,
+// without calling fn, if the dependencies form a cycle.
+func (g *Graph) WalkTopo(fn func(name string) error) error {
+	if g.hasCycle() {
+		return ErrCycle
+	}
+	walked := make(StringSet, len(g.actions))
+
+	var walk func(name string) error
+	walk = func(name string) error {
+		walked.Add(name)
+		for dep := range g.treeOrder[name] {
+			if walked.Contains(dep) {
+				continue
+			}
+			if err := walk(dep); err != nil {
+				return err
+			}
+		}
+		if _, ok := g.actions[name]; !ok {
+			return nil
+		}
+		return fn(name)
+	}
+
+	for name := range g.actions {
+		if walked.Contains(name) {
+			continue
+		}
+		if err := walk(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EstimatedCriticalPath returns the path through this Graph, from an action with
+// no dependencies to an action with no dependents, whose actions have the greatest
+// total cost, along with that cost. Actions added without a cost cost nothing.
+// Ties are broken by name. ErrCycle is returned if the Graph has a cycle.
+func (g *Graph) EstimatedCriticalPath() ([]string, time.Duration, error) {
+	costs := make(map[string]time.Duration, len(g.actions))
+	slowest := make(map[string]string, len(g.actions))
+	visiting := make(StringSet)
+
+	var cost func(name string) (time.Duration, error)
+	cost = func(name string) (time.Duration, error) {
+		if c, ok := costs[name]; ok {
+			return c, nil
+		}
+		if visiting.Contains(name) {
+			return 0, ErrCycle
+		}
+		visiting.Add(name)
+		var longest time.Duration
+		for _, dep := range sortedNames(g.treeOrder[name]) {
+			depCost, err := cost(dep)
+			if err != nil {
+				return 0, err
+			}
+			if _, ok := slowest[name]; !ok || depCost > longest {
+				slowest[name], longest = dep, depCost
+			}
+		}
+		visiting.Remove(name)
+		costs[name] = longest + g.costs[name]
+		return costs[name], nil
+	}
+
+	names := make([]string, 0, len(g.actions))
+	for name := range g.actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var end string
+	var total time.Duration
+	for _, name := range names {
+		c, err := cost(name)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(g.graphOrder[name]) == 0 && (end == "" || c > total) {
+			end, total = name, c
+		}
+	}
+	if end == "" {
+		return nil, 0, nil
+	}
+
+	var path []string
+	for name, ok := end, true; ok; name, ok = slowest[name] {
+		path = append(path, name)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path, total, nil
+}
+
+// FindCycles returns every elementary cycle in this Graph, found with Johnson's algorithm.
+// Each cycle lists its actions in dependency order, beginning with the least name,
+// so that each action depends on the one before it and the first depends on the last.
+// Cycles are ordered by their first action, then by the order they were found.
+func (g *Graph) FindCycles() [][]string {
+	all := make(StringSet, len(g.actions))
+	for name := range g.actions {
+		all.Add(name)
+	}
+	for name, dependents := range g.graphOrder {
+		all.Add(name)
+		for dependent := range dependents {
+			all.Add(dependent)
+		}
+	}
+	names := sortedNames(all)
+	index := make(map[string]int, len(names))
+	for i, name := range names {
+		index[name] = i
+	}
+
+	var cycles [][]string
+	for i, start := range names {
+		// Only search the subgraph of actions not before start,
+		// so each cycle is found exactly once, from its least action
+		blocked := make(StringSet)
+		blockedBy := make(stringmultimap)
+		var stack []string
+
+		var unblock func(name string)
+		unblock = func(name string) {
+			blocked.Remove(name)
+			waits := blockedBy[name]
+			delete(blockedBy, name)
+			for waiting := range waits {
+				if blocked.Contains(waiting) {
+					unblock(waiting)
+				}
+			}
+		}
+
+		var circuit func(name string) bool
+		circuit = func(name string) bool {
+			found := false
+			stack = append(stack, name)
+			blocked.Add(name)
+			dependents := sortedNames(g.graphOrder[name])
+			for _, dependent := range dependents {
+				if index[dependent] < i {
+					continue
+				}
+				if dependent == start {
+					cycles = append(cycles, append([]string(nil), stack...))
+					found = true
+				} else if !blocked.Contains(dependent) && circuit(dependent) {
+					found = true
+				}
+			}
+			if found {
+				unblock(name)
+			} else {
+				for _, dependent := range dependents {
+					if index[dependent] >= i {
+						blockedBy.Add(dependent, name)
+					}
+				}
+			}
+			stack = stack[:len(stack)-1]
+			return found
+		}
+		circuit(start)
+	}
+	return cycles
+}
+
+// TransitiveDependents returns the sorted names of every action that depends on
+// the action name, directly or transitively
+func (g *Graph) TransitiveDependents(name string) ([]string, error) {
+	return g.closure(name, g.graphOrder)
+}
+
+// TransitiveDependencies returns the sorted names of every action that the
+// action name depends on, directly or transitively
+func (g *Graph) TransitiveDependencies(name string) ([]string, error) {
+	return g.closure(name, g.treeOrder)
+}
+
+// Paths returns every path of dependencies from the action from to the action to,
+// such that each action on a path is a dependency of the next, in sorted order.
+// A path never visits an action twice, so Paths is bounded even if the Graph has a
+// cycle, but the number of paths can grow exponentially with the size of the Graph:
+// PathExists answers whether there are any in linear time. The only path from an
+// action to itself is that action alone. An error is returned if either action does
+// not exist.
+func (g *Graph) Paths(from, to string) ([][]string, error) {
+	for _, name := range []string{from, to} {
+		if _, ok := g.actions[name]; !ok {
+			return nil, errors.Errorf("action %q does not exist", name)
+		}
+	}
+	// Only actions that to depends on can be on a path to it
+	ancestors := g.reachable(to, g.treeOrder)
+
+	var paths [][]string
+	path := []string{from}
+	onPath := StringSet{from: {}}
+	var walk func(name string)
+	walk = func(name string) {
+		if name == to {
+			paths = append(paths, append([]string(nil), path...))
+			return
+		}
+		for _, dependent := range sortedNames(g.graphOrder[name]) {
+			if onPath.Contains(dependent) || !ancestors.Contains(dependent) {
+				continue
+			}
+			path = append(path, dependent)
+			onPath.Add(dependent)
+			walk(dependent)
+			onPath.Remove(dependent)
+			path = path[:len(path)-1]
+		}
+	}
+	walk(from)
+	return paths, nil
+}
+
+// PathExists returns if the action to depends on the action from, directly or
+// transitively, or they are the same action. It is false if either does not exist.
+func (g *Graph) PathExists(from, to string) bool {
+	for _, name := range []string{from, to} {
+		if _, ok := g.actions[name]; !ok {
+			return false
+		}
+	}
+	return g.reachable(from, g.graphOrder).Contains(to)
+}
+
+// closure returns the sorted names reachable from name in adjacency, excluding name
+func (g *Graph) closure(name string, adjacency stringmultimap) ([]string, error) {
+	if _, ok := g.actions[name]; !ok {
+		return nil, errors.Errorf("action %q does not exist", name)
+	}
+	reached := g.reachable(name, adjacency)
+	reached.Remove(name)
+	return sortedNames(reached), nil
+}
+
+// reachable returns the set of names reachable from name in adjacency, including name
+func (g *Graph) reachable(name string, adjacency stringmultimap) StringSet {
+	reached := StringSet{name: {}}
+	pending := []string{name}
+	for len(pending) > 0 {
+		next := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		for adjacent := range adjacency[next] {
+			if !reached.Contains(adjacent) {
+				reached.Add(adjacent)
+				pending = append(pending, adjacent)
+			}
+		}
+	}
+	return reached
+}
+
+// String renders the dependency tree of this Graph, beginning with each action
+// that has no dependencies and listing the dependents of every action indented
+// beneath it. An action whose dependents were already listed is marked with
+// "..." instead of repeating them, and an action that depends on itself
+// through a cycle is marked with "(cycle)".
+func (g *Graph) String() string {
+	buf := &bytes.Buffer{}
+	expanded := make(StringSet)
+	path := make(StringSet)
+
+	var write func(name string, depth int)
+	write = func(name string, depth int) {
+		for i := 0; i < depth; i++ {
+			buf.WriteString("  ")
+		}
+		buf.WriteString(name)
+		switch {
+		case path.Contains(name):
+			buf.WriteString(" (cycle)\n")
+			return
+		case expanded.Contains(name):
+			if len(g.graphOrder[name]) > 0 {
+				buf.WriteString(" ...")
+			}
+			buf.WriteRune('\n')
+			return
+		}
+		buf.WriteRune('\n')
+
+		expanded.Add(name)
+		path.Add(name)
+		for _, dependent := range sortedNames(g.graphOrder[name]) {
+			write(dependent, depth+1)
+		}
+		path.Remove(name)
+	}
+
+	names := make(StringSet, len(g.actions))
+	for name := range g.actions {
+		names.Add(name)
+	}
+	sorted := sortedNames(names)
+	for _, name := range sorted {
+		if len(g.treeOrder[name]) == 0 {
+			write(name, 0)
+		}
+	}
+	// Actions that only exist within cycles are not reachable from above
+	for _, name := range sorted {
+		if !expanded.Contains(name) {
+			write(name, 0)
+		}
+	}
+
+	return buf.String()
+}
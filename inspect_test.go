@@ -0,0 +1,672 @@
+package depfunc
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraph_Orphans(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", sampleaction)
+	g.AddAction("b", sampleaction)
+	g.AddAction("c", sampleaction)
+	g.AddAction("d", sampleaction)
+	g.LinkDependency("a", "b")
+
+	orphans := g.Orphans()
+
+	assert.Equal(t, []string{"c", "d"}, orphans)
+}
+
+func TestGraph_Orphans_none(t *testing.T) {
+	g := definedGraph(t)
+
+	orphans := g.Orphans()
+
+	assert.Len(t, orphans, 0)
+}
+
+func TestGraph_Validate(t *testing.T) {
+	g := definedGraph(t)
+
+	err := g.Validate()
+
+	assert.NoError(t, err)
+}
+
+func TestGraph_Validate_empty(t *testing.T) {
+	g := NewGraph()
+
+	err := g.Validate()
+
+	assert.Equal(t, ErrNoRoots, err)
+}
+
+func TestGraph_Validate_noRoots(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", sampleaction)
+	g.AddAction("b", sampleaction)
+	g.LinkDependency("a", "b")
+	g.LinkDependency("b", "a")
+
+	err := g.Validate()
+
+	assert.Equal(t, ErrNoRoots, err)
+}
+
+func TestGraph_Validate_cycle(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", sampleaction)
+	g.AddAction("b", sampleaction)
+	g.AddAction("c", sampleaction)
+	g.AddAction("d", sampleaction)
+	g.LinkDependency("a", "b")
+	g.LinkDependency("b", "c")
+	g.LinkDependency("c", "b")
+	g.LinkDependency("a", "d")
+	g.LinkDependency("c", "d")
+
+	err := g.Validate()
+
+	assert.Equal(t, ErrCycle, err)
+}
+
+func TestGraph_Validate_deadBranches(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", sampleaction)
+	g.AddAction("b", sampleaction)
+	g.AddAction("c", sampleaction)
+	g.AddAction("d", sampleaction)
+	g.LinkDependency("a", "b")
+	g.LinkDependency("b", "c")
+	g.LinkDependency("c", "b")
+	g.LinkDependency("a", "d")
+
+	err := g.Validate()
+
+	assert.EqualError(t, err, "dead branches can never run: b, c")
+}
+
+func TestGraph_DeadBranches(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", sampleaction)
+	g.AddAction("b", sampleaction)
+	g.AddAction("c", sampleaction)
+	g.AddAction("d", sampleaction)
+	g.AddAction("x", sampleaction)
+	g.LinkDependency("a", "b")
+	g.LinkDependency("b", "c")
+	g.LinkDependency("c", "b")
+	g.LinkDependency("a", "d")
+	g.graphOrder.Add("x", "removed")
+
+	assert.Equal(t, []string{"b", "c", "x"}, g.DeadBranches())
+	assert.Len(t, definedGraph(t).DeadBranches(), 0)
+}
+
+func TestGraph_Validate_unknownLinks(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", sampleaction)
+	g.AddAction("b", sampleaction)
+	g.LinkDependency("a", "b")
+	g.treeOrder.Add("b", "x")
+	g.graphOrder.Add("y", "a")
+
+	err := g.Validate()
+
+	assert.EqualError(t, err, "links reference unknown actions: x, y")
+}
+
+func TestGraph_Equal(t *testing.T) {
+	a := definedGraph(t)
+	b := definedGraph(t)
+
+	assert.True(t, a.Equal(b))
+	assert.True(t, b.Equal(a))
+}
+
+func TestGraph_Equal_ignoresActions(t *testing.T) {
+	a := NewGraph()
+	a.AddAction("a", sampleaction)
+	b := NewGraph()
+	b.AddAction("a", visitorAction("a"))
+
+	assert.True(t, a.Equal(b))
+}
+
+func TestGraph_Equal_nil(t *testing.T) {
+	g := NewGraph()
+
+	assert.False(t, g.Equal(nil))
+}
+
+func TestGraph_Equal_differentActions(t *testing.T) {
+	a := NewGraph()
+	a.AddAction("a", sampleaction)
+	b := NewGraph()
+	b.AddAction("b", sampleaction)
+
+	assert.False(t, a.Equal(b))
+}
+
+func TestGraph_Equal_differentEdges(t *testing.T) {
+	a := definedGraph(t)
+	b := definedGraph(t)
+	b.LinkDependency("f", "k")
+
+	assert.False(t, a.Equal(b))
+	assert.False(t, b.Equal(a))
+}
+
+func TestGraph_Equal_reversedEdge(t *testing.T) {
+	a := NewGraph()
+	a.AddAction("a", sampleaction)
+	a.AddAction("b", sampleaction)
+	a.LinkDependency("a", "b")
+	b := NewGraph()
+	b.AddAction("a", sampleaction)
+	b.AddAction("b", sampleaction)
+	b.LinkDependency("b", "a")
+
+	assert.False(t, a.Equal(b))
+}
+
+func TestGraph_Levels(t *testing.T) {
+	g := definedGraph(t)
+
+	levels, err := g.Levels()
+
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"a"},
+		{"b", "c", "d", "h"},
+		{"e", "g", "i"},
+		{"f", "j", "k"},
+	}, levels)
+}
+
+func TestGraph_WalkTopo(t *testing.T) {
+	g := definedGraph(t)
+	var walked []string
+
+	err := g.WalkTopo(func(name string) error {
+		walked = append(walked, name)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k"}, walked)
+	order := strings.Join(walked, "")
+	for _, edge := range g.Edges() {
+		assert.True(t, strings.Index(order, edge[0]) < strings.Index(order, edge[1]), "%s before %s", edge[0], edge[1])
+	}
+}
+
+func TestGraph_WalkTopo_stop(t *testing.T) {
+	g := definedGraph(t)
+	stop := errors.New("stop")
+	walked := 0
+
+	err := g.WalkTopo(func(name string) error {
+		walked++
+		if walked == 3 {
+			return stop
+		}
+		return nil
+	})
+
+	assert.Equal(t, stop, err)
+	assert.Equal(t, 3, walked)
+}
+
+func TestGraph_WalkTopo_cycle(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", sampleaction)
+	g.AddAction("b", sampleaction)
+	g.LinkDependency("a", "b")
+	g.LinkDependency("b", "a")
+
+	err := g.WalkTopo(func(name string) error {
+		t.Fatalf("walked %s", name)
+		return nil
+	})
+
+	assert.Equal(t, ErrCycle, err)
+}
+
+func TestGraph_Levels_longestPath(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("apples", sampleaction)
+	g.AddAction("sugars", sampleaction)
+	g.AddAction("metals", sampleaction)
+	g.AddAction("cans", sampleaction)
+	g.AddAction("applesauce", sampleaction)
+	g.AddAction("qa", sampleaction)
+	g.LinkDependency("metals", "cans")
+	g.LinkDependency("apples", "applesauce")
+	g.LinkDependency("sugars", "applesauce")
+	g.LinkDependency("cans", "applesauce")
+	g.LinkDependency("apples", "qa")
+
+	levels, err := g.Levels()
+
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"apples", "metals", "sugars"},
+		{"cans", "qa"},
+		{"applesauce"},
+	}, levels)
+}
+
+func TestGraph_Levels_empty(t *testing.T) {
+	g := NewGraph()
+
+	levels, err := g.Levels()
+
+	assert.NoError(t, err)
+	assert.Len(t, levels, 0)
+}
+
+func TestGraph_Levels_cycle(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", sampleaction)
+	g.AddAction("b", sampleaction)
+	g.AddAction("c", sampleaction)
+	g.LinkDependency("a", "b")
+	g.LinkDependency("b", "c")
+	g.LinkDependency("c", "b")
+
+	levels, err := g.Levels()
+
+	assert.Equal(t, ErrCycle, err)
+	assert.Nil(t, levels)
+}
+
+func TestGraph_EstimatedCriticalPath(t *testing.T) {
+	g := NewGraph()
+	g.AddActionWithCost("apples", sampleaction, 5*time.Minute)
+	g.AddActionWithCost("sugar", sampleaction, time.Minute)
+	g.AddActionWithCost("peel", sampleaction, 10*time.Minute)
+	g.AddActionWithCost("cook", sampleaction, 30*time.Minute)
+	g.AddAction("serve", sampleaction)
+	g.LinkDependency("apples", "peel")
+	g.LinkDependency("peel", "cook")
+	g.LinkDependency("sugar", "cook")
+	g.LinkDependency("cook", "serve")
+
+	path, cost, err := g.EstimatedCriticalPath()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"apples", "peel", "cook", "serve"}, path)
+	assert.Equal(t, 45*time.Minute, cost)
+}
+
+func TestGraph_EstimatedCriticalPath_tie(t *testing.T) {
+	g := NewGraph()
+	g.AddActionWithCost("b", sampleaction, time.Second)
+	g.AddActionWithCost("a", sampleaction, time.Second)
+	g.AddAction("c", sampleaction)
+	g.LinkDependency("b", "c")
+	g.LinkDependency("a", "c")
+
+	path, cost, err := g.EstimatedCriticalPath()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "c"}, path)
+	assert.Equal(t, time.Second, cost)
+}
+
+func TestGraph_EstimatedCriticalPath_empty(t *testing.T) {
+	path, cost, err := NewGraph().EstimatedCriticalPath()
+
+	assert.NoError(t, err)
+	assert.Nil(t, path)
+	assert.Equal(t, time.Duration(0), cost)
+}
+
+func TestGraph_EstimatedCriticalPath_cycle(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", sampleaction)
+	g.AddAction("b", sampleaction)
+	g.LinkDependency("a", "b")
+	g.LinkDependency("b", "a")
+
+	path, _, err := g.EstimatedCriticalPath()
+
+	assert.Equal(t, ErrCycle, err)
+	assert.Nil(t, path)
+}
+
+func TestGraph_Edges(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("apples", sampleaction)
+	g.AddAction("sugar", sampleaction)
+	g.AddAction("applesauce", sampleaction)
+	g.AddAction("cans", sampleaction)
+	g.LinkDependency("sugar", "applesauce")
+	g.LinkDependency("apples", "applesauce")
+	g.LinkDependency("applesauce", "cans")
+	g.LinkDependency("apples", "cans")
+
+	assert.Equal(t, [][2]string{
+		{"apples", "applesauce"},
+		{"apples", "cans"},
+		{"applesauce", "cans"},
+		{"sugar", "applesauce"},
+	}, g.Edges())
+}
+
+func TestGraph_Edges_none(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", sampleaction)
+
+	assert.Nil(t, g.Edges())
+}
+
+func TestGraph_EdgeCount(t *testing.T) {
+	g := definedGraph(t)
+
+	assert.Equal(t, len(g.Edges()), g.EdgeCount())
+	assert.Equal(t, 0, NewGraph().EdgeCount())
+}
+
+func TestGraph_FanOut(t *testing.T) {
+	g := NewGraph()
+	for _, name := range []string{"a", "b", "c", "d"} {
+		g.AddAction(name, sampleaction)
+	}
+	g.LinkDependency("b", "c")
+	g.LinkDependency("b", "d")
+	g.LinkDependency("a", "c")
+	g.LinkDependency("a", "d")
+
+	assert.Equal(t, FanOutStats{Max: 2, MaxAction: "a", Mean: 1}, g.FanOut())
+}
+
+func TestGraph_FanOut_empty(t *testing.T) {
+	assert.Equal(t, FanOutStats{}, NewGraph().FanOut())
+}
+
+func TestGraph_FindCycles(t *testing.T) {
+	g := NewGraph()
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		g.AddAction(name, sampleaction)
+	}
+	g.LinkDependency("a", "b")
+	g.LinkDependency("b", "c")
+	g.LinkDependency("c", "a")
+	g.LinkDependency("c", "b")
+	g.LinkDependency("d", "e")
+	g.LinkDependency("e", "d")
+	g.LinkDependency("f", "f")
+
+	cycles := g.FindCycles()
+
+	assert.Equal(t, [][]string{
+		{"a", "b", "c"},
+		{"b", "c"},
+		{"d", "e"},
+		{"f"},
+	}, cycles)
+}
+
+func TestGraph_FindCycles_none(t *testing.T) {
+	g := definedGraph(t)
+
+	assert.Nil(t, g.FindCycles())
+}
+
+func TestGraph_TransitiveDependents(t *testing.T) {
+	g := definedGraph(t)
+
+	dependents, err := g.TransitiveDependents("b")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"e", "f"}, dependents)
+}
+
+func TestGraph_TransitiveDependencies(t *testing.T) {
+	g := definedGraph(t)
+
+	dependencies, err := g.TransitiveDependencies("f")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "e"}, dependencies)
+}
+
+func TestGraph_Paths(t *testing.T) {
+	g := NewGraph()
+	for _, name := range []string{"apples", "sugars", "sauce", "cans", "qa", "shipping"} {
+		g.AddAction(name, sampleaction)
+	}
+	g.LinkDependency("apples", "sauce")
+	g.LinkDependency("sugars", "sauce")
+	g.LinkDependency("apples", "qa")
+	g.LinkDependency("sauce", "cans")
+	g.LinkDependency("cans", "qa")
+	g.LinkDependency("qa", "shipping")
+
+	paths, err := g.Paths("apples", "qa")
+
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"apples", "qa"},
+		{"apples", "sauce", "cans", "qa"},
+	}, paths)
+	assert.True(t, g.PathExists("apples", "qa"))
+	assert.True(t, g.PathExists("qa", "qa"))
+	assert.False(t, g.PathExists("qa", "apples"))
+	assert.False(t, g.PathExists("sugars", "apples"))
+	assert.False(t, g.PathExists("missing", "qa"))
+}
+
+func TestGraph_Paths_none(t *testing.T) {
+	g := definedGraph(t)
+
+	paths, err := g.Paths("f", "a")
+
+	assert.NoError(t, err)
+	assert.Empty(t, paths)
+}
+
+func TestGraph_Paths_self(t *testing.T) {
+	g := definedGraph(t)
+
+	paths, err := g.Paths("a", "a")
+
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"a"}}, paths)
+}
+
+func TestGraph_Paths_cycle(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", sampleaction)
+	g.AddAction("b", sampleaction)
+	g.AddAction("c", sampleaction)
+	g.LinkDependency("a", "b")
+	g.LinkDependency("b", "c")
+	g.LinkDependency("c", "b")
+
+	paths, err := g.Paths("a", "c")
+
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"a", "b", "c"}}, paths)
+}
+
+func TestGraph_Paths_unknown(t *testing.T) {
+	g := definedGraph(t)
+
+	_, err := g.Paths("a", "missing")
+
+	assert.EqualError(t, err, `action "missing" does not exist`)
+}
+
+func TestGraph_TransitiveDependents_cycle(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", sampleaction)
+	g.AddAction("b", sampleaction)
+	g.AddAction("c", sampleaction)
+	g.LinkDependency("a", "b")
+	g.LinkDependency("b", "c")
+	g.LinkDependency("c", "a")
+
+	dependents, err := g.TransitiveDependents("a")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b", "c"}, dependents)
+}
+
+func TestGraph_TransitiveDependents_unknown(t *testing.T) {
+	g := NewGraph()
+
+	dependents, err := g.TransitiveDependents("missing")
+
+	assert.EqualError(t, err, `action "missing" does not exist`)
+	assert.Nil(t, dependents)
+}
+
+func TestGraph_String(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("apples", sampleaction)
+	g.AddAction("sugars", sampleaction)
+	g.AddAction("metals", sampleaction)
+	g.AddAction("cans", sampleaction)
+	g.AddAction("applesauce", sampleaction)
+	g.AddAction("qa", sampleaction)
+	g.LinkDependency("metals", "cans")
+	g.LinkDependency("apples", "applesauce")
+	g.LinkDependency("sugars", "applesauce")
+	g.LinkDependency("cans", "applesauce")
+	g.LinkDependency("cans", "qa")
+	g.LinkDependency("qa", "applesauce")
+
+	out := g.String()
+
+	assert.Equal(t, `apples
+  applesauce
+metals
+  cans
+    applesauce
+    qa
+      applesauce
+sugars
+  applesauce
+`, out)
+}
+
+func TestGraph_String_shared(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", sampleaction)
+	g.AddAction("b", sampleaction)
+	g.AddAction("c", sampleaction)
+	g.AddAction("d", sampleaction)
+	g.LinkDependency("a", "c")
+	g.LinkDependency("b", "c")
+	g.LinkDependency("c", "d")
+
+	out := g.String()
+
+	assert.Equal(t, `a
+  c
+    d
+b
+  c ...
+`, out)
+}
+
+func TestGraph_String_cycle(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", sampleaction)
+	g.AddAction("b", sampleaction)
+	g.AddAction("c", sampleaction)
+	g.LinkDependency("b", "c")
+	g.LinkDependency("c", "b")
+
+	out := g.String()
+
+	assert.Equal(t, `a
+b
+  c
+    b (cycle)
+`, out)
+}
+
+func TestGraph_String_empty(t *testing.T) {
+	g := NewGraph()
+
+	assert.Equal(t, "", g.String())
+}
+
+func TestGraph_Lint(t *testing.T) {
+	g := NewGraph()
+	for _, name := range []string{"apples", "sugars", "metals", "cans", "applesauce"} {
+		g.AddAction(name, sampleaction)
+	}
+	g.LinkDependency("metals", "cans")
+	g.LinkFanIn("applesauce", "apples", "sugars", "cans")
+
+	assert.Empty(t, g.Lint("applesauce"))
+}
+
+func TestGraph_Lint_reversed(t *testing.T) {
+	g := NewGraph()
+	for _, name := range []string{"apples", "sugars", "cans", "applesauce"} {
+		g.AddAction(name, sampleaction)
+	}
+	g.LinkFanOut("applesauce", "apples", "sugars", "cans")
+
+	warnings := g.Lint()
+
+	assert.Len(t, warnings, 1)
+	assert.Equal(t, `the graph begins at "applesauce" alone and ends at apples, cans, sugars: if "applesauce" is its output, its links are reversed`, warnings[0].String())
+}
+
+func TestGraph_Lint_outputs(t *testing.T) {
+	g := NewGraph()
+	for _, name := range []string{"apples", "sugars", "applesauce"} {
+		g.AddAction(name, sampleaction)
+	}
+	g.LinkFanOut("applesauce", "apples", "sugars")
+
+	warnings := g.Lint("applesauce")
+
+	assert.Equal(t, []LintWarning{{
+		Message: `output "applesauce" is a dependency of apples, sugars: its links may be reversed`,
+		Edges:   [][2]string{{"applesauce", "apples"}, {"applesauce", "sugars"}},
+	}}, warnings)
+}
+
+func TestGraph_RunnableCount(t *testing.T) {
+	g := definedGraph(t)
+	prior := NewStatistics()
+	prior.Recorder().Enter("f")
+	prior.Recorder().Start("f")
+	prior.Recorder().Finish("f")
+
+	for _, opts := range [][]ResolveOption{
+		nil,
+		{WithSkip(skipNames("h"))},
+		{WithResume(prior)},
+	} {
+		log, recorder := NewEventLog()
+		r, err := g.Start(testContext(), newVisitordata(), append(opts, WithRecorders(recorder))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Wait()
+
+		entered := 0
+		for _, e := range log.Events() {
+			if e.Kind == EventEnter {
+				entered++
+			}
+		}
+		assert.Equal(t, entered, g.RunnableCount(opts...))
+	}
+	assert.Equal(t, 11, g.RunnableCount())
+	assert.Equal(t, 0, NewGraph().RunnableCount())
+}
@@ -0,0 +1,152 @@
+package depfunc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// JSONRecorder is a Recorder that writes each event to an io.Writer
+// as a line of JSON, such as:
+//
+//	{"name":"apples","event":"start","t":"2018-02-15T00:00:00Z"}
+//
+// Errors and swallowed errors are written with an "error" field, retries
+// with an "attempt" field, panics with a "panic" field, and events of a
+// resolve identified by WithID with an "id" field.
+type JSONRecorder struct {
+	*jsonWriter
+
+	// id is the ID of the resolve being recorded, if any
+	id string
+}
+
+// jsonWriter is the destination shared by a JSONRecorder and its identified copies
+type jsonWriter struct {
+	mx  *sync.Mutex
+	w   io.Writer
+	err error
+
+	// now returns the time of an event
+	now func() time.Time
+}
+
+// jsonEvent is a line written by a JSONRecorder
+type jsonEvent struct {
+	Name    string    `json:"name"`
+	Event   string    `json:"event"`
+	T       time.Time `json:"t"`
+	ID      string    `json:"id,omitempty"`
+	Error   string    `json:"error,omitempty"`
+	Attempt int       `json:"attempt,omitempty"`
+	Panic   string    `json:"panic,omitempty"`
+}
+
+// NewJSONRecorder creates a JSONRecorder that writes to w.
+// Writes are serialized, so w does not need to be safe for concurrent use.
+func NewJSONRecorder(w io.Writer) *JSONRecorder {
+	return &JSONRecorder{
+		jsonWriter: &jsonWriter{
+			mx:  &sync.Mutex{},
+			w:   w,
+			now: time.Now,
+		},
+	}
+}
+
+// WithID returns a JSONRecorder that writes to the same io.Writer,
+// tagging each event with id
+func (r *JSONRecorder) WithID(id string) Recorder {
+	return &JSONRecorder{jsonWriter: r.jsonWriter, id: id}
+}
+
+// Err returns the first error encountered writing an event, if any.
+// Once writing fails, no further events are written.
+func (r *JSONRecorder) Err() error {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	return r.err
+}
+
+func (r *JSONRecorder) write(name string, kind EventKind, err error) {
+	r.writeEvent(Event{Name: name, Kind: kind, Err: err})
+}
+
+func (r *JSONRecorder) writeEvent(event Event) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	if r.err != nil {
+		return
+	}
+	e := jsonEvent{Name: event.Name, Event: event.Kind.String(), T: r.now(), ID: r.id, Attempt: event.Attempt}
+	if event.Err != nil {
+		e.Error = event.Err.Error()
+	}
+	if event.Value != nil {
+		e.Panic = fmt.Sprint(event.Value)
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		r.err = err
+		return
+	}
+	_, r.err = r.w.Write(append(line, '\n'))
+}
+
+func (r *JSONRecorder) Enter(name string) {
+	r.write(name, EventEnter, nil)
+}
+
+func (r *JSONRecorder) Start(name string) {
+	r.write(name, EventStart, nil)
+}
+
+func (r *JSONRecorder) Finish(name string) {
+	r.write(name, EventFinish, nil)
+}
+
+func (r *JSONRecorder) Exit(name string) {
+	r.write(name, EventExit, nil)
+}
+
+func (r *JSONRecorder) Error(name string, err error) {
+	r.write(name, EventError, err)
+}
+
+func (r *JSONRecorder) Swallow(name string, err error) {
+	r.write(name, EventSwallow, err)
+}
+
+func (r *JSONRecorder) Skip(name string) {
+	r.write(name, EventSkip, nil)
+}
+
+func (r *JSONRecorder) Gate(name string) {
+	r.write(name, EventGate, nil)
+}
+
+func (r *JSONRecorder) Retry(name string, attempt int, err error) {
+	r.writeEvent(Event{Name: name, Kind: EventRetry, Err: err, Attempt: attempt})
+}
+
+func (r *JSONRecorder) GiveUp(name string, reason error) {
+	r.write(name, EventGiveUp, reason)
+}
+
+func (r *JSONRecorder) NoChange(name string) {
+	r.write(name, EventNoChange, nil)
+}
+
+func (r *JSONRecorder) Timeout(name string) {
+	r.write(name, EventTimeout, nil)
+}
+
+func (r *JSONRecorder) Panic(name string, v interface{}) {
+	r.writeEvent(Event{Name: name, Kind: EventPanic, Value: v})
+}
+
+func (r *JSONRecorder) Abort(name string) {
+	r.write(name, EventAbort, nil)
+}
@@ -0,0 +1,93 @@
+package depfunc
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type failingWriter struct {
+	writes int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return 0, errors.Errorf("write %d failed", w.writes)
+}
+
+func TestJSONRecorder(t *testing.T) {
+	buf := &bytes.Buffer{}
+	recorder := NewJSONRecorder(buf)
+	recorder.now = func() time.Time { return at(0) }
+
+	recorder.Start("apples")
+	recorder.Swallow("telemetry", errors.New("offline"))
+
+	assert.Equal(t, `{"name":"apples","event":"start","t":"2018-02-15T00:00:00Z"}
+{"name":"telemetry","event":"swallow","t":"2018-02-15T00:00:00Z","error":"offline"}
+`, buf.String())
+	assert.NoError(t, recorder.Err())
+}
+
+func TestJSONRecorder_resolved(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", visitorAction("a"))
+	g.AddAction("b", visitorAction("b"))
+	g.LinkDependency("a", "b")
+	buf := &bytes.Buffer{}
+	recorder := NewJSONRecorder(buf)
+
+	ctx, err := g.Resolve(testContext(), newVisitordata(), recorder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-ctx.Done()
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	assert.Len(t, lines, 8)
+	assert.NoError(t, recorder.Err())
+}
+
+func TestJSONRecorder_writeError(t *testing.T) {
+	w := &failingWriter{}
+	recorder := NewJSONRecorder(w)
+
+	recorder.Enter("a")
+	recorder.Exit("a")
+
+	assert.EqualError(t, recorder.Err(), "write 1 failed")
+	assert.Equal(t, 1, w.writes)
+}
+
+func TestJSONRecorder_WithID(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", visitorAction("a"))
+	buf := &bytes.Buffer{}
+	recorder := NewJSONRecorder(buf)
+	recorder.now = func() time.Time { return at(0) }
+
+	ctx, err := g.ResolveWith(testContext(), newVisitordata(), WithRecorders(recorder), WithID("first"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-ctx.Done()
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if assert.Len(t, lines, 4) {
+		assert.Equal(t, `{"name":"a","event":"enter","t":"2018-02-15T00:00:00Z","id":"first"}`, lines[0])
+	}
+}
+
+func TestJSONRecorder_Panic(t *testing.T) {
+	buf := &bytes.Buffer{}
+	recorder := NewJSONRecorder(buf)
+	recorder.now = func() time.Time { return at(0) }
+
+	recorder.Panic("a", "boom")
+
+	assert.Equal(t, `{"name":"a","event":"panic","t":"2018-02-15T00:00:00Z","panic":"boom"}`+"\n", buf.String())
+}
@@ -0,0 +1,310 @@
+package depfunc
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Node is a handle to an Action while it executes.
+// An Action retrieves its Node from its context with NodeFromContext.
+type Node struct {
+	name     string
+	g        *Graph
+	s        search
+	recorder Recorder
+
+	// mx guards sealed, dependents and completion
+	mx *sync.Mutex
+
+	// sealed is set once the Action has returned
+	sealed bool
+
+	// dependents are the wait groups of the actions added by AddDependent
+	dependents []*sync.WaitGroup
+
+	// completion is done once the background work of an AsyncAction is, if it has any
+	completion context.Context
+
+	// inherited are the context values passed by the Action's dependencies
+	inherited map[interface{}]interface{}
+
+	// values are the context values passed to the Action's dependents,
+	// if it has set any with SetContextValue
+	values map[interface{}]interface{}
+}
+
+type nodeKey struct{}
+
+// withNode returns a context carrying node
+func withNode(ctx context.Context, node *Node) context.Context {
+	return context.WithValue(ctx, nodeKey{}, node)
+}
+
+// NodeFromContext returns the Node of the Action executing with ctx
+func NodeFromContext(ctx context.Context) (*Node, bool) {
+	node, ok := ctx.Value(nodeKey{}).(*Node)
+	return node, ok
+}
+
+// NameFromContext returns the name of the Action executing with ctx,
+// so that an Action can be written without knowing the name it is added under
+func NameFromContext(ctx context.Context) (string, bool) {
+	node, ok := NodeFromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	return node.name, true
+}
+
+// GraphView is a read-only view of the Graph an Action executes in, as it was
+// when the resolve began, so that a generic Action, such as one aggregating over
+// all of its inputs, can find its neighbours instead of hardcoding their names.
+// Actions added by Node.AddDependent are not part of the view.
+type GraphView struct {
+	name string
+	g    *Graph
+}
+
+// GraphFromContext returns a view of the Graph of the Action executing with ctx
+func GraphFromContext(ctx context.Context) (*GraphView, bool) {
+	node, ok := NodeFromContext(ctx)
+	if !ok {
+		return nil, false
+	}
+	return &GraphView{name: node.name, g: node.g}, true
+}
+
+// Name returns the name of the current Action
+func (v *GraphView) Name() string {
+	return v.name
+}
+
+// Dependencies returns the sorted names of the actions that the action name,
+// such as the current Action, depends on directly
+func (v *GraphView) Dependencies(name string) []string {
+	return sortedNames(v.g.treeOrder[name])
+}
+
+// Dependents returns the sorted names of the actions that depend directly
+// on the action name, such as the current Action
+func (v *GraphView) Dependents(name string) []string {
+	return sortedNames(v.g.graphOrder[name])
+}
+
+// Name returns the name of the Action
+func (n *Node) Name() string {
+	return n.name
+}
+
+// ResolveContext returns the context of the resolve the Action executes in.
+// The context an Action is given is derived from it, and also ends once the
+// Action's own timeout passes, so an Action can tell the two apart: if its
+// context is done while ResolveContext is not, only the Action was cancelled.
+func (n *Node) ResolveContext() context.Context {
+	return n.s.ctx
+}
+
+// Unchanged returns if the Action name, such as a dependency of the current
+// Action, finished by returning ErrNoChange
+func (n *Node) Unchanged(name string) bool {
+	return n.s.unchanged.Contains(name)
+}
+
+// DependenciesUnchanged returns if the current Action has dependencies and
+// every one of them returned ErrNoChange, in which case the current Action
+// may have nothing to do either
+func (n *Node) DependenciesUnchanged() bool {
+	dependencies := n.s.dependencies(n.g, n.name)
+	if len(dependencies) == 0 {
+		return false
+	}
+	for dependency := range dependencies {
+		if !n.s.unchanged.Contains(dependency) {
+			return false
+		}
+	}
+	return true
+}
+
+// AddDependent schedules action to execute under name within the same resolve,
+// once the current Action has returned. It allows a resolve to grow as work is discovered.
+//
+// AddDependent may be called from any goroutine, but only while the current Action
+// is executing: once the Action returns, an error is returned. The name must not be
+// used by any action in the Graph or added earlier in the same resolve. The added action
+// depends only on the current Action, nothing depends on it, and it may add dependents
+// of its own. It is wrapped by the Graph's middleware and reported to recorders like any
+// other action, and the resolve is not done until it has exited. Like any other
+// dependent, it is aborted if the current Action fails or its context ends early,
+// and skipped if the current Action returns ErrSkipDependents.
+func (n *Node) AddDependent(name string, action ActionE) error {
+	if name == "" {
+		return ErrEmptyName
+	}
+	if action == nil {
+		return ErrNilAction
+	}
+	if _, exists := n.g.actions[name]; exists {
+		return errors.Errorf("action %q already exists", name)
+	}
+
+	n.mx.Lock()
+	defer n.mx.Unlock()
+	if n.sealed {
+		return errors.Errorf("action %q has already finished", n.name)
+	}
+	if !n.s.added.tryAdd(name, n.name) {
+		return errors.Errorf("action %q already exists", name)
+	}
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	n.dependents = append(n.dependents, wg)
+	n.g.launch(n.s, name, n.g.wrap(name, action), wg, n.recorder)
+	return nil
+}
+
+// addedActions holds the actions added while resolving by Node.AddDependent,
+// each with the action that added it, which is its only dependency
+type addedActions struct {
+	mx      *sync.RWMutex
+	parents map[string]string
+}
+
+func newAddedActions() *addedActions {
+	return &addedActions{
+		mx:      &sync.RWMutex{},
+		parents: make(map[string]string),
+	}
+}
+
+// tryAdd adds name as a dependent of parent unless it was already added, returning if it was added
+func (a *addedActions) tryAdd(name, parent string) bool {
+	a.mx.Lock()
+	defer a.mx.Unlock()
+	if _, exists := a.parents[name]; exists {
+		return false
+	}
+	a.parents[name] = parent
+	return true
+}
+
+// parent returns the action that added name, or false if name was not added
+func (a *addedActions) parent(name string) (string, bool) {
+	a.mx.RLock()
+	defer a.mx.RUnlock()
+	parent, ok := a.parents[name]
+	return parent, ok
+}
+
+// names returns the set of added actions
+func (a *addedActions) names() StringSet {
+	a.mx.RLock()
+	defer a.mx.RUnlock()
+	names := make(StringSet, len(a.parents))
+	for name := range a.parents {
+		names.Add(name)
+	}
+	return names
+}
+
+// nodeCancels holds the cancel functions of the contexts of executing actions,
+// and the actions cancelled by Resolution.CancelNode
+type nodeCancels struct {
+	mx        *sync.Mutex
+	cancels   map[string]context.CancelFunc
+	cancelled StringSet
+}
+
+func newNodeCancels() *nodeCancels {
+	return &nodeCancels{
+		mx:        &sync.Mutex{},
+		cancels:   make(map[string]context.CancelFunc),
+		cancelled: make(StringSet),
+	}
+}
+
+// register records the cancel function of the executing action name,
+// calling it at once if name was cancelled as it started
+func (c *nodeCancels) register(name string, cancel context.CancelFunc) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	if c.cancelled.Contains(name) {
+		cancel()
+		return
+	}
+	c.cancels[name] = cancel
+}
+
+// unregister forgets the cancel function of name once it has returned,
+// returning if it was cancelled
+func (c *nodeCancels) unregister(name string) bool {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	delete(c.cancels, name)
+	return c.cancelled.Contains(name)
+}
+
+// cancel marks name as cancelled, cancelling its context if it is executing
+func (c *nodeCancels) cancel(name string) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.cancelled.Add(name)
+	if cancel, ok := c.cancels[name]; ok {
+		cancel()
+	}
+}
+
+// cancelAll cancels every executing action
+func (c *nodeCancels) cancelAll() {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	for name, cancel := range c.cancels {
+		c.cancelled.Add(name)
+		cancel()
+	}
+}
+
+// isCancelled returns if name was cancelled
+func (c *nodeCancels) isCancelled(name string) bool {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	return c.cancelled.Contains(name)
+}
+
+// seal prevents further dependents and context values from being added,
+// returning the wait groups of the dependents that were
+func (n *Node) seal() []*sync.WaitGroup {
+	n.mx.Lock()
+	defer n.mx.Unlock()
+	n.sealed = true
+	return n.dependents
+}
+
+// completeWith has the Action finish once complete is done, rather than when it returns
+func (n *Node) completeWith(complete context.Context) {
+	n.mx.Lock()
+	n.completion = complete
+	n.mx.Unlock()
+}
+
+// takeCompletion returns and forgets the context given to completeWith, if any
+func (n *Node) takeCompletion() context.Context {
+	n.mx.Lock()
+	defer n.mx.Unlock()
+	complete := n.completion
+	n.completion = nil
+	return complete
+}
+
+// passed returns the context values the Action passes to its dependents
+func (n *Node) passed() map[interface{}]interface{} {
+	n.mx.Lock()
+	defer n.mx.Unlock()
+	if n.values == nil {
+		return n.inherited
+	}
+	return n.values
+}
@@ -0,0 +1,296 @@
+package depfunc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNodeFromContext(t *testing.T) {
+	g := NewGraph()
+	names := make(chan string, 1)
+	g.AddAction("a", func(ctx context.Context, arg interface{}) {
+		node, ok := NodeFromContext(ctx)
+		if ok {
+			names <- node.Name()
+		}
+		close(names)
+	})
+
+	ctx, err := g.Resolve(testContext(), nil)
+	<-ctx.Done()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "a", <-names)
+}
+
+func TestNodeFromContext_missing(t *testing.T) {
+	node, ok := NodeFromContext(context.Background())
+
+	assert.False(t, ok)
+	assert.Nil(t, node)
+}
+
+func TestGraphFromContext(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("apples", sampleaction)
+	g.AddAction("sugars", sampleaction)
+	g.AddAction("qa", sampleaction)
+	views := make(chan *GraphView, 1)
+	g.AddAction("applesauce", func(ctx context.Context, arg interface{}) {
+		if view, ok := GraphFromContext(ctx); ok {
+			views <- view
+		}
+		close(views)
+	})
+	g.LinkFanIn("applesauce", "sugars", "apples")
+	g.LinkDependency("apples", "qa")
+
+	ctx, err := g.Resolve(testContext(), newVisitordata())
+	<-ctx.Done()
+	g.LinkDependency("applesauce", "qa")
+
+	assert.NoError(t, err)
+	view := <-views
+	if assert.NotNil(t, view) {
+		assert.Equal(t, "applesauce", view.Name())
+		assert.Equal(t, []string{"apples", "sugars"}, view.Dependencies(view.Name()))
+		assert.Empty(t, view.Dependents(view.Name()))
+		assert.Equal(t, []string{"applesauce", "qa"}, view.Dependents("apples"))
+		assert.Empty(t, view.Dependencies("missing"))
+	}
+}
+
+func TestGraphFromContext_missing(t *testing.T) {
+	view, ok := GraphFromContext(context.Background())
+
+	assert.False(t, ok)
+	assert.Nil(t, view)
+}
+
+func TestNode_ResolveContext(t *testing.T) {
+	g := NewGraph()
+	errs := make(chan error, 2)
+	g.AddAction("a", func(ctx context.Context, arg interface{}) {
+		<-ctx.Done()
+		node, _ := NodeFromContext(ctx)
+		errs <- ctx.Err()
+		errs <- node.ResolveContext().Err()
+	})
+
+	r, err := g.Start(testContext(), nil, WithActionTimeout("a", time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Wait()
+
+	assert.Equal(t, context.DeadlineExceeded, <-errs)
+	assert.NoError(t, <-errs)
+}
+
+func TestNode_ResolveContext_cancelled(t *testing.T) {
+	g := NewGraph()
+	started := make(chan struct{})
+	errs := make(chan error, 2)
+	g.AddAction("a", func(ctx context.Context, arg interface{}) {
+		close(started)
+		<-ctx.Done()
+		node, _ := NodeFromContext(ctx)
+		errs <- ctx.Err()
+		errs <- node.ResolveContext().Err()
+	})
+
+	r, err := g.Start(testContext(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	r.Cancel()
+	r.Wait()
+
+	assert.Equal(t, context.Canceled, <-errs)
+	assert.Equal(t, context.Canceled, <-errs)
+}
+
+// namedAction visits the name it was added under
+func namedAction(ctx context.Context, arg interface{}) {
+	if name, ok := NameFromContext(ctx); ok {
+		arg.(*visitordata).Visit(name)
+	}
+}
+
+func TestNameFromContext(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", namedAction)
+	g.AddAction("b", namedAction)
+	g.LinkDependency("a", "b")
+
+	visitorData := newVisitordata()
+	ctx, err := g.Resolve(testContext(), visitorData)
+	<-ctx.Done()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, visitorData.visited)
+}
+
+func TestNameFromContext_missing(t *testing.T) {
+	name, ok := NameFromContext(context.Background())
+
+	assert.False(t, ok)
+	assert.Equal(t, "", name)
+}
+
+func TestNode_AddDependent(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", func(ctx context.Context, arg interface{}) {
+		node, _ := NodeFromContext(ctx)
+		if err := node.AddDependent("a2", func(ctx context.Context, arg interface{}) error {
+			node, _ := NodeFromContext(ctx)
+			arg.(*visitordata).Visit(node.Name())
+			return node.AddDependent("a3", func(ctx context.Context, arg interface{}) error {
+				arg.(*visitordata).Visit("a3")
+				return nil
+			})
+		}); err != nil {
+			panic(err)
+		}
+		arg.(*visitordata).Visit("a")
+	})
+	g.AddAction("b", visitorAction("b"))
+	g.LinkDependency("a", "b")
+	visitorData := newVisitordata()
+	stats := NewStatistics()
+
+	r, err := g.Start(testContext(), visitorData, WithRecorders(stats.Recorder()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-r.Done()
+
+	if assert.Len(t, visitorData.visited, 4) {
+		assert.Equal(t, "a", visitorData.visited[0])
+	}
+	assert.Equal(t, []string{"a", "a2", "a3"}, filterNames(visitorData.visited, "a", "a2", "a3"))
+	assert.Equal(t, stringSet("a", "a2", "a3", "b"), stats.Names())
+}
+
+// addingDependent returns an action that adds a visitor action named name as its
+// dependent and then returns err, once its context is done if wait is true
+func addingDependent(name string, wait bool, err error) ActionE {
+	return func(ctx context.Context, arg interface{}) error {
+		node, _ := NodeFromContext(ctx)
+		if addErr := node.AddDependent(name, actionE(visitorAction(name))); addErr != nil {
+			return addErr
+		}
+		if wait {
+			<-ctx.Done()
+		}
+		return err
+	}
+}
+
+func TestNode_AddDependent_failedParent(t *testing.T) {
+	g := NewGraph()
+	g.AddActionE("a", addingDependent("a2", false, errors.New("failed a")))
+	visitorData := newVisitordata()
+
+	events, err := g.ResolveStreamWith(testContext(), visitorData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := collectEvents(events)
+
+	assert.Empty(t, visitorData.visited)
+	assert.NotEqual(t, -1, indexOfEvent(out, "a2", EventAbort))
+	assert.Equal(t, -1, indexOfEvent(out, "a2", EventStart))
+}
+
+func TestNode_AddDependent_timedOutParent(t *testing.T) {
+	g := NewGraph()
+	g.AddActionContinueOnError("a", addingDependent("a2", true, nil))
+	visitorData := newVisitordata()
+
+	events, err := g.ResolveStreamWith(testContext(), visitorData, WithActionTimeout("a", time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := collectEvents(events)
+
+	assert.Empty(t, visitorData.visited)
+	assert.NotEqual(t, -1, indexOfEvent(out, "a2", EventAbort))
+	assert.Equal(t, -1, indexOfEvent(out, "a2", EventStart))
+}
+
+func TestNode_AddDependent_skipParent(t *testing.T) {
+	g := NewGraph()
+	g.AddActionE("a", addingDependent("a2", false, ErrSkipDependents))
+	visitorData := newVisitordata()
+
+	r, err := g.Start(testContext(), visitorData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, r.Wait())
+	assert.Empty(t, visitorData.visited)
+	assert.Equal(t, StatusSkipped, r.Status()["a2"])
+}
+
+func TestNode_AddDependent_errors(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("b", sampleaction)
+	errs := make(chan error, 5)
+	var saved *Node
+	noop := func(ctx context.Context, arg interface{}) error { return nil }
+	g.AddAction("a", func(ctx context.Context, arg interface{}) {
+		node, _ := NodeFromContext(ctx)
+		saved = node
+		errs <- node.AddDependent("", noop)
+		errs <- node.AddDependent("b", noop)
+		errs <- node.AddDependent("c", noop)
+		errs <- node.AddDependent("c", noop)
+		errs <- node.AddDependent("d", nil)
+	})
+
+	ctx, err := g.Resolve(testContext(), nil)
+	<-ctx.Done()
+
+	assert.NoError(t, err)
+	assert.EqualError(t, <-errs, "name must not be empty")
+	assert.EqualError(t, <-errs, `action "b" already exists`)
+	assert.NoError(t, <-errs)
+	assert.EqualError(t, <-errs, `action "c" already exists`)
+	assert.EqualError(t, <-errs, "action must not be nil")
+	assert.EqualError(t, saved.AddDependent("d", noop), `action "a" has already finished`)
+}
+
+func filterNames(names []string, keep ...string) []string {
+	k := stringSet(keep...)
+	var out []string
+	for _, name := range names {
+		if k.Contains(name) {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+func TestNode_DependenciesUnchanged_noDependencies(t *testing.T) {
+	g := NewGraph()
+	unchanged := make(chan bool, 1)
+	g.AddAction("a", func(ctx context.Context, arg interface{}) {
+		node, _ := NodeFromContext(ctx)
+		unchanged <- node.DependenciesUnchanged()
+	})
+
+	r, err := g.Start(testContext(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Wait()
+
+	assert.False(t, <-unchanged)
+}
@@ -0,0 +1,520 @@
+package depfunc
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// ResolveOption configures a single resolve of a Graph
+type ResolveOption func(*resolveConfig)
+
+// resolveConfig is the configuration of a single resolve
+type resolveConfig struct {
+	// recorders are the Recorders that monitor the resolve
+	recorders []Recorder
+
+	// skips are the predicates of actions to skip
+	skips []func(name string) bool
+
+	// tagFilter is the set of tags an action must have one of to be executed
+	tagFilter StringSet
+
+	// deadlineAt is the time by which the resolve must finish, if not zero
+	deadlineAt time.Time
+
+	// timeout is how long the resolve may take from when it begins, if not zero
+	timeout time.Duration
+
+	// args provides each action its argument, if not nil
+	args func(name string) interface{}
+
+	// sorted is whether actions are traversed in sorted order
+	sorted bool
+
+	// lazy is whether goroutines are only launched for ready actions
+	lazy bool
+
+	// insertionOrder is whether actions are traversed in the order they were added
+	insertionOrder bool
+
+	// serial is whether ready actions execute one at a time in the order they were added
+	serial bool
+
+	// allowEmpty is whether a graph with no roots resolves successfully
+	allowEmpty bool
+
+	// timeouts is the map of actions to how long they may execute
+	timeouts map[string]time.Duration
+
+	// executor runs actions whose dependencies are satisfied, if not nil
+	executor func(task func())
+
+	// resumed is the set of actions that finished in a prior resolve
+	resumed StringSet
+
+	// id identifies the resolve to recorders, if not empty
+	id string
+
+	// seed is the seed to shuffle the traversal order with, if seeded
+	seed   int64
+	seeded bool
+
+	// pruneDeadBranches is whether dead branches are left out of the resolve instead of failing it
+	pruneDeadBranches bool
+
+	// scratch is the memory to reuse for the bookkeeping of the resolve, if not nil
+	scratch *Scratch
+
+	// gates decide whether each action may execute as it is about to start
+	gates []Gate
+
+	// eager is whether actions start without waiting for every goroutine to be launched
+	eager bool
+
+	// retries is the map of actions to how they are retried when they fail
+	retries map[string]RetryPolicy
+
+	// until is the action whose finishing ends the resolve, if not empty
+	until string
+
+	// untilCancels is whether executing actions are cancelled once until finishes
+	untilCancels bool
+
+	// overrides is the map of actions executed in place of those of the graph
+	overrides map[string]ActionE
+
+	// beforeEach are called before each action executes
+	beforeEach []func(ctx context.Context, name string)
+
+	// afterEach are called after each action executes
+	afterEach []func(ctx context.Context, name string, err error)
+}
+
+func newResolveConfig(opts []ResolveOption) *resolveConfig {
+	cfg := &resolveConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// WithRecorders monitors the resolve with recorders
+func WithRecorders(recorders ...Recorder) ResolveOption {
+	return func(cfg *resolveConfig) {
+		cfg.recorders = append(cfg.recorders, recorders...)
+	}
+}
+
+// WithSkip skips every action for which skip returns true.
+// A skipped action is not executed, and neither is any action that
+// depends on it, directly or transitively, since its dependencies can
+// never be satisfied. The dependencies of a skipped action are not affected.
+// Skipped actions are reported to SkipRecorders and are never entered.
+func WithSkip(skip func(name string) bool) ResolveOption {
+	return func(cfg *resolveConfig) {
+		cfg.skips = append(cfg.skips, skip)
+	}
+}
+
+// WithTagFilter skips every action that does not have at least one of tags,
+// with the same semantics as WithSkip: an action that has one of tags is still
+// skipped if anything it depends on was filtered out. With no tags every action
+// is skipped.
+func WithTagFilter(tags ...string) ResolveOption {
+	return func(cfg *resolveConfig) {
+		if cfg.tagFilter == nil {
+			cfg.tagFilter = make(StringSet)
+		}
+		for _, tag := range tags {
+			cfg.tagFilter.Add(tag)
+		}
+	}
+}
+
+// WithDeadline cancels the resolve if it has not finished by t.
+// A Resolution reports this with an error that matches context.DeadlineExceeded.
+func WithDeadline(t time.Time) ResolveOption {
+	return func(cfg *resolveConfig) {
+		cfg.deadlineAt = t
+	}
+}
+
+// WithTimeout cancels the resolve if it has not finished within d of beginning.
+// A Resolution reports this with an error that matches context.DeadlineExceeded.
+func WithTimeout(d time.Duration) ResolveOption {
+	return func(cfg *resolveConfig) {
+		cfg.timeout = d
+	}
+}
+
+// WithArgs gives each Action the arg returned by args for its name,
+// in place of the arg passed to the resolve
+func WithArgs(args func(name string) interface{}) ResolveOption {
+	return func(cfg *resolveConfig) {
+		cfg.args = args
+	}
+}
+
+// argsOr returns the configured args, or args that always return arg
+func (cfg *resolveConfig) argsOr(arg interface{}) func(name string) interface{} {
+	if cfg.args != nil {
+		return cfg.args
+	}
+	return func(string) interface{} {
+		return arg
+	}
+}
+
+// WithSortedSetup traverses the Graph in sorted order while setting up the
+// resolve, so that Enter events are recorded in the same order every time
+// a given Graph is resolved. The order in which Actions execute is unaffected.
+func WithSortedSetup() ResolveOption {
+	return func(cfg *resolveConfig) {
+		cfg.sorted = true
+	}
+}
+
+// WithInsertionOrder traverses the Graph in the order its Actions were added
+// while setting up the resolve, like WithSortedSetup, over which it takes
+// precedence. With WithLazyLaunch, the Actions that are ready as the resolve
+// begins are also launched in that order. The order in which Actions execute
+// is otherwise unaffected: Serial makes it deterministic too.
+func WithInsertionOrder() ResolveOption {
+	return func(cfg *resolveConfig) {
+		cfg.insertionOrder = true
+	}
+}
+
+// Serial executes one Action at a time: of the Actions whose dependencies have
+// finished, the one added to the Graph first executes next. Resolving a Graph
+// of deterministic Actions then has the same side effects in the same order
+// every time, for golden-file tests, for instance. It implies WithLazyLaunch
+// and WithInsertionOrder, and replaces WithExecutor and WithScheduler. Actions
+// added by Node.AddDependent are not executed serially.
+func Serial() ResolveOption {
+	return func(cfg *resolveConfig) {
+		cfg.serial = true
+	}
+}
+
+// WithLazyLaunch only launches the goroutine for an Action once all of its
+// dependencies have exited, rather than launching one for every Action
+// up front that waits for its dependencies. The number of live goroutines
+// is then proportional to the work that is ready to run, not to the size of
+// the Graph, at the cost of no Action starting until the whole Graph has
+// been traversed. Actions added with Node.AddDependent are launched eagerly.
+func WithLazyLaunch() ResolveOption {
+	return func(cfg *resolveConfig) {
+		cfg.lazy = true
+	}
+}
+
+// WithActionTimeout fails the Action name with context.DeadlineExceeded if it
+// executes for longer than timeout, and the context given to it is done once the
+// timeout elapses. Like any error, this cancels the resolve unless the Action
+// was added with AddActionContinueOnError, and the Actions that depend on it are
+// aborted either way rather than executing without its result.
+func WithActionTimeout(name string, timeout time.Duration) ResolveOption {
+	return func(cfg *resolveConfig) {
+		if cfg.timeouts == nil {
+			cfg.timeouts = make(map[string]time.Duration)
+		}
+		cfg.timeouts[name] = timeout
+	}
+}
+
+// UntilFinished ends the resolve early once the Action name has finished without
+// error, for when only its output is needed. No further Actions start after it:
+// those that have not started are skipped, which is reported to SkipRecorders,
+// and the resolve does not fail. If cancelRunning, Actions that are still
+// executing have their context cancelled and exit without finishing, like
+// Resolution.CancelNode; otherwise they are left to finish. Either way, the
+// resolve is done once they have exited. If name fails, is skipped or is not
+// in the Graph, the resolve is unaffected.
+func UntilFinished(name string, cancelRunning bool) ResolveOption {
+	return func(cfg *resolveConfig) {
+		cfg.until = name
+		cfg.untilCancels = cancelRunning
+	}
+}
+
+// WithOverrides executes the Actions in overrides in place of the Graph's Actions
+// of the same names, for this resolve only, such as to substitute a fake in a test
+// or a new implementation in a canary. The Graph itself is unchanged. Overrides
+// are wrapped by the Graph's middleware and otherwise treated like the Actions
+// they replace. Names that are not in the Graph, and nil Actions, are ignored.
+func WithOverrides(overrides map[string]Action) ResolveOption {
+	return func(cfg *resolveConfig) {
+		if cfg.overrides == nil {
+			cfg.overrides = make(map[string]ActionE, len(overrides))
+		}
+		for name, action := range overrides {
+			if action == nil {
+				continue
+			}
+			action := action
+			cfg.overrides[name] = func(ctx context.Context, arg interface{}) error {
+				action(ctx, arg)
+				return nil
+			}
+		}
+	}
+}
+
+// RetryPolicy is how an Action that fails is retried. It is retried until it
+// succeeds or it runs out of Attempts or Budget, whichever comes first.
+type RetryPolicy struct {
+	// Attempts is how many times the Action may be executed in all, or no limit if not positive
+	Attempts int
+
+	// Budget is how long after it was first started the Action may be executed again, or no limit if not positive.
+	// An attempt that has started is not cut short once Budget passes: WithActionTimeout bounds all
+	// the attempts together, ending the context they are all given once it passes.
+	Budget time.Duration
+}
+
+// WithRetry retries the Action name by policy when it fails, instead of failing at once.
+// Each retry is reported to RetryRecorders, as is the reason the Action stopped being
+// retried if it never succeeded. An Action is not retried once its context is done.
+// A policy with neither Attempts nor Budget is ignored, rather than retrying forever.
+func WithRetry(name string, policy RetryPolicy) ResolveOption {
+	return func(cfg *resolveConfig) {
+		if policy.Attempts <= 0 && policy.Budget <= 0 {
+			return
+		}
+		if cfg.retries == nil {
+			cfg.retries = make(map[string]RetryPolicy)
+		}
+		cfg.retries[name] = policy
+	}
+}
+
+// WithExecutor submits each Action to executor once its dependencies are
+// satisfied, instead of executing it on a goroutine of its own, so that Actions
+// can run on an existing pool of workers. The executor must run every task it
+// is given. Tasks are submitted from within other tasks, so a bounded executor
+// must queue a task rather than wait for a worker to be free, or it can deadlock.
+//
+// Without WithLazyLaunch, a goroutine still waits for the dependencies of each
+// Action before submitting it, so the two are best used together.
+func WithExecutor(executor func(task func())) ResolveOption {
+	return func(cfg *resolveConfig) {
+		cfg.executor = executor
+	}
+}
+
+// WithScheduler executes Actions with scheduler, which bounds how many execute
+// at once across every resolve that shares it and has the resolves take turns.
+// Like WithExecutor, which it replaces, it is best used with WithLazyLaunch.
+// An Action must not wait for a resolve that uses the same Scheduler, such as
+// a subgraph, or the resolves can deadlock once every slot is taken.
+func WithScheduler(scheduler *Scheduler) ResolveOption {
+	return func(cfg *resolveConfig) {
+		cfg.executor = scheduler.executor()
+	}
+}
+
+// WithResume resumes a resolve recorded by prior, so that only the Actions that
+// did not finish successfully in it are executed. Actions that finished without
+// an error are treated as already done: their dependents do not wait for them,
+// and they and anything only they depend on are not entered again.
+// prior is read as the resolve begins. If it is nil, nothing is resumed.
+func WithResume(prior *Statistics) ResolveOption {
+	return func(cfg *resolveConfig) {
+		if prior == nil {
+			return
+		}
+		succeeded := prior.Snapshot().Succeeded()
+		if cfg.resumed == nil {
+			cfg.resumed = make(StringSet)
+		}
+		for name := range succeeded {
+			cfg.resumed.Add(name)
+		}
+	}
+}
+
+// WithID identifies the resolve as id, so that the events of resolves that run
+// concurrently can be told apart. Recorders that implement IdentifiedRecorder
+// are replaced by the Recorder returned by their WithID for the resolve,
+// and other Recorders are unaffected.
+func WithID(id string) ResolveOption {
+	return func(cfg *resolveConfig) {
+		cfg.id = id
+	}
+}
+
+// identifiedRecorders returns the configured recorders, identified by the resolve's ID if it has one
+func (cfg *resolveConfig) identifiedRecorders() []Recorder {
+	if cfg.id == "" {
+		return cfg.recorders
+	}
+	recorders := make([]Recorder, len(cfg.recorders))
+	for i, recorder := range cfg.recorders {
+		if ir, ok := recorder.(IdentifiedRecorder); ok {
+			recorder = ir.WithID(cfg.id)
+		}
+		recorders[i] = recorder
+	}
+	return recorders
+}
+
+// AllowEmpty resolves a Graph with no roots, such as an empty Graph,
+// successfully and without executing anything, instead of returning ErrNoRoots.
+// The returned context is already done.
+func AllowEmpty() ResolveOption {
+	return func(cfg *resolveConfig) {
+		cfg.allowEmpty = true
+	}
+}
+
+// PruneDeadBranches resolves a Graph with dead branches by leaving the actions
+// of the dead branches out of the resolve, instead of returning an error naming
+// them. See Graph.DeadBranches for when an action is part of a dead branch.
+func PruneDeadBranches() ResolveOption {
+	return func(cfg *resolveConfig) {
+		cfg.pruneDeadBranches = true
+	}
+}
+
+// WithScratch reuses scratch for the bookkeeping of the resolve instead of allocating it.
+// scratch must not be in use by another resolve that is not yet done.
+func WithScratch(scratch *Scratch) ResolveOption {
+	return func(cfg *resolveConfig) {
+		cfg.scratch = scratch
+	}
+}
+
+// Gate decides whether an Action may execute, as it is about to start
+type Gate interface {
+	// Allow returns if the Action name may execute. It may be called
+	// concurrently, and consult live state such as an open circuit breaker.
+	Allow(name string) bool
+}
+
+// GateFunc is a func that is a Gate
+type GateFunc func(name string) bool
+
+// Allow returns f(name)
+func (f GateFunc) Allow(name string) bool {
+	return f(name)
+}
+
+// WithGates consults gates as each Action is about to start, once its dependencies
+// are satisfied. If any gate does not allow an Action, it is not executed and is
+// reported to GateRecorders and SkipRecorders, and its dependents are skipped,
+// without failing the resolve. Unlike WithSkip, gates decide at execution time.
+func WithGates(gates ...Gate) ResolveOption {
+	return func(cfg *resolveConfig) {
+		cfg.gates = append(cfg.gates, gates...)
+	}
+}
+
+// WithBeforeEach calls before as each Action is about to execute, synchronously,
+// on the Action's goroutine and with its context, so that it can set up resources
+// for the Action, such as opening a transaction. Unlike Middleware, it does not
+// replace the Action, and unlike a Recorder, the Action does not start until it
+// returns. It is called once for an Action that is retried, and not at all for
+// an Action that does not start, such as one that is skipped.
+func WithBeforeEach(before func(ctx context.Context, name string)) ResolveOption {
+	return func(cfg *resolveConfig) {
+		cfg.beforeEach = append(cfg.beforeEach, before)
+	}
+}
+
+// WithAfterEach calls after once each Action has returned, synchronously, on the
+// Action's goroutine and with its context, so that it can tear down what WithBeforeEach
+// set up, such as closing a transaction. err is what the Action returned, after
+// any retries: nil, ErrSkipDependents or ErrNoChange if it succeeded. Hooks given
+// by several WithAfterEach options are called in reverse, like deferred calls.
+func WithAfterEach(after func(ctx context.Context, name string, err error)) ResolveOption {
+	return func(cfg *resolveConfig) {
+		cfg.afterEach = append(cfg.afterEach, after)
+	}
+}
+
+// EagerStart lets each Action start as soon as its own dependencies are satisfied,
+// without waiting for the goroutines of every other Action to be launched. The
+// Graph is still traversed in full first, so a cycle fails the resolve before any
+// Action starts, but no goroutines are launched while traversing: they are all
+// launched once it is complete, in reverse of the order they were visited, which
+// puts dependencies ahead of their dependents. This shortens the time until the
+// first Actions finish in large Graphs.
+// It has no effect with WithLazyLaunch, which already launches ready Actions first.
+func EagerStart() ResolveOption {
+	return func(cfg *resolveConfig) {
+		cfg.eager = true
+	}
+}
+
+// WithSchedulerSeed traverses the Graph in a random order while setting up the
+// resolve, so that Actions are entered, and with WithLazyLaunch first launched,
+// in an order that shakes out Actions that depend on an order they were never
+// promised. The order is the same every time a given Graph is resolved with the
+// same seed. It takes precedence over WithSortedSetup.
+func WithSchedulerSeed(seed int64) ResolveOption {
+	return func(cfg *resolveConfig) {
+		cfg.seed = seed
+		cfg.seeded = true
+	}
+}
+
+// shuffle returns the random source to shuffle the traversal order with, if seeded
+func (cfg *resolveConfig) shuffle() *rand.Rand {
+	if !cfg.seeded {
+		return nil
+	}
+	return rand.New(rand.NewSource(cfg.seed))
+}
+
+// deadline returns the earliest configured deadline, if there is one
+func (cfg *resolveConfig) deadline() (time.Time, bool) {
+	deadline := cfg.deadlineAt
+	if cfg.timeout > 0 {
+		timeout := time.Now().Add(cfg.timeout)
+		if deadline.IsZero() || timeout.Before(deadline) {
+			deadline = timeout
+		}
+	}
+	return deadline, !deadline.IsZero()
+}
+
+// skipped returns the set of actions that will not be executed with cfg:
+// the actions that were skipped and all of their transitive dependents
+func (g *Graph) skipped(cfg *resolveConfig) StringSet {
+	skipped := make(StringSet)
+	if len(cfg.skips) == 0 && cfg.tagFilter == nil {
+		return skipped
+	}
+	for name := range g.actions {
+		if cfg.skip(g, name) {
+			g.skipDependents(skipped, name)
+		}
+	}
+	return skipped
+}
+
+// skip returns if name itself should be skipped
+func (cfg *resolveConfig) skip(g *Graph, name string) bool {
+	if cfg.tagFilter != nil && len(g.tags[name].Intersect(cfg.tagFilter)) == 0 {
+		return true
+	}
+	for _, skip := range cfg.skips {
+		if skip(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// skipDependents adds name and everything that depends on it to skipped
+func (g *Graph) skipDependents(skipped StringSet, name string) {
+	if skipped.Contains(name) {
+		return
+	}
+	skipped.Add(name)
+	for dependent := range g.graphOrder[name] {
+		g.skipDependents(skipped, dependent)
+	}
+}
@@ -0,0 +1,848 @@
+package depfunc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func skipNames(names ...string) func(name string) bool {
+	skip := stringSet(names...)
+	return skip.Contains
+}
+
+func TestGraph_skipped(t *testing.T) {
+	g := definedGraph(t)
+
+	skipped := g.skipped(newResolveConfig([]ResolveOption{WithSkip(skipNames("b", "i"))}))
+
+	assert.Equal(t, stringSet("b", "e", "f", "i", "j", "k"), skipped)
+}
+
+func TestGraph_skipped_none(t *testing.T) {
+	g := definedGraph(t)
+
+	skipped := g.skipped(newResolveConfig(nil))
+
+	assert.Len(t, skipped, 0)
+}
+
+func TestGraph_skipped_tagFilter(t *testing.T) {
+	g := NewGraph()
+	g.AddActionWithTags("a", sampleaction, "qa")
+	g.AddActionWithTags("b", sampleaction, "qa", "prod")
+	g.AddActionWithTags("c", sampleaction, "prod")
+	g.AddActionWithTags("d", sampleaction, "qa")
+	g.LinkDependency("a", "b")
+	g.LinkDependency("c", "d")
+
+	skipped := g.skipped(newResolveConfig([]ResolveOption{WithTagFilter("qa")}))
+
+	assert.Equal(t, stringSet("c", "d"), skipped)
+}
+
+func TestGraph_skipped_emptyTagFilter(t *testing.T) {
+	g := definedGraph(t)
+
+	skipped := g.skipped(newResolveConfig([]ResolveOption{WithTagFilter()}))
+
+	assert.Len(t, skipped, len(g.actions))
+}
+
+func TestGraph_ResolveWith_skip(t *testing.T) {
+	g := definedGraph(t)
+	visitorData := newVisitordata()
+	recorder := newSkipRecorder()
+
+	ctx, err := g.ResolveWith(testContext(), visitorData, WithSkip(skipNames("b", "i")), WithRecorders(recorder))
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-ctx.Done()
+
+	visited := strings.Join(visitorData.visited, "")
+	assert.Len(t, visited, 5)
+	assertOccursBefore(t, 'a', "cdh", visited)
+	assertOccursBefore(t, 'c', "g", visited)
+	assert.Equal(t, stringSet("b", "e", "f", "i", "j", "k"), recorder.skipped.Copy())
+}
+
+func TestGraph_ResolveWith_tagFilter(t *testing.T) {
+	g := NewGraph()
+	g.AddActionWithTags("a", visitorAction("a"), "qa")
+	g.AddActionWithTags("b", visitorAction("b"), "qa")
+	g.AddActionWithTags("c", visitorAction("c"), "prod")
+	g.AddActionWithTags("d", visitorAction("d"), "qa")
+	g.LinkDependency("a", "b")
+	g.LinkDependency("c", "d")
+	visitorData := newVisitordata()
+
+	ctx, err := g.ResolveWith(testContext(), visitorData, WithTagFilter("qa"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-ctx.Done()
+
+	assert.Equal(t, []string{"a", "b"}, visitorData.visited)
+}
+
+type skipRecorder struct {
+	*noopVisitRecorder
+	skipped *SyncStringSet
+}
+
+func newSkipRecorder() *skipRecorder {
+	return &skipRecorder{skipped: NewSyncStringSet()}
+}
+
+func (r *skipRecorder) Skip(name string) {
+	r.skipped.Add(name)
+}
+
+func TestResolveConfig_deadline(t *testing.T) {
+	cfg := newResolveConfig(nil)
+
+	_, ok := cfg.deadline()
+
+	assert.False(t, ok)
+}
+
+func TestResolveConfig_deadline_earliest(t *testing.T) {
+	later := time.Now().Add(time.Hour)
+	cfg := newResolveConfig([]ResolveOption{WithDeadline(later), WithTimeout(time.Minute)})
+
+	deadline, ok := cfg.deadline()
+
+	assert.True(t, ok)
+	assert.True(t, deadline.Before(later))
+
+	sooner := time.Now().Add(time.Second)
+	cfg = newResolveConfig([]ResolveOption{WithDeadline(sooner), WithTimeout(time.Minute)})
+
+	deadline, ok = cfg.deadline()
+
+	assert.True(t, ok)
+	assert.Equal(t, sooner, deadline)
+}
+
+type enterRecorder struct {
+	*noopVisitRecorder
+	mx      *sync.Mutex
+	entered []string
+}
+
+func (r *enterRecorder) Enter(name string) {
+	r.mx.Lock()
+	r.entered = append(r.entered, name)
+	r.mx.Unlock()
+}
+
+func TestGraph_ResolveWith_sortedSetup(t *testing.T) {
+	g := definedGraph(t)
+
+	for i := 0; i < 10; i++ {
+		recorder := &enterRecorder{mx: &sync.Mutex{}}
+		ctx, err := g.ResolveWith(testContext(), newVisitordata(), WithSortedSetup(), WithRecorders(recorder))
+		if err != nil {
+			t.Fatal(err)
+		}
+		<-ctx.Done()
+
+		assert.Equal(t, []string{"d", "a", "f", "e", "b", "g", "c", "j", "i", "h", "k"}, recorder.entered)
+	}
+}
+
+func TestGraph_ResolveWith_insertionOrder(t *testing.T) {
+	g := NewGraph()
+	for _, name := range []string{"c", "b", "d", "a"} {
+		g.AddAction(name, sampleaction)
+	}
+	g.LinkDependency("d", "a")
+
+	for i := 0; i < 10; i++ {
+		recorder := &enterRecorder{mx: &sync.Mutex{}}
+		r, err := g.Start(testContext(), newVisitordata(), WithInsertionOrder(), WithSortedSetup(), WithRecorders(recorder))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.NoError(t, r.Wait())
+
+		assert.Equal(t, []string{"c", "b", "a", "d"}, recorder.entered)
+	}
+}
+
+func TestGraph_ResolveWith_serial(t *testing.T) {
+	//  z   a
+	//  |\  |
+	//  m  y
+	g := NewGraph()
+	for _, name := range []string{"z", "m", "a", "y"} {
+		g.AddAction(name, visitorAction(name))
+	}
+	g.LinkFanOut("z", "m", "y")
+	g.LinkDependency("a", "y")
+	var running, peak int32
+	g.Use(func(name string, next Action) Action {
+		return func(ctx context.Context, arg interface{}) {
+			n := atomic.AddInt32(&running, 1)
+			for p := atomic.LoadInt32(&peak); n > p && !atomic.CompareAndSwapInt32(&peak, p, n); p = atomic.LoadInt32(&peak) {
+			}
+			next(ctx, arg)
+			atomic.AddInt32(&running, -1)
+		}
+	})
+
+	for i := 0; i < 20; i++ {
+		visitorData := newVisitordata()
+		r, err := g.Start(testContext(), visitorData, Serial(), WithExecutor(func(task func()) {
+			t.Error("executor must not be used")
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.NoError(t, r.Wait())
+
+		assert.Equal(t, []string{"z", "m", "a", "y"}, visitorData.visited)
+	}
+	assert.Equal(t, int32(1), peak)
+}
+
+func TestGraph_ResolveWith_lazyLaunch(t *testing.T) {
+	g := definedGraph(t)
+
+	visitorData := newVisitordata()
+	ctx, err := g.ResolveWith(testContext(), visitorData, WithLazyLaunch())
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-ctx.Done()
+
+	assert.Len(t, visitorData.visited, 11)
+	assertOccursBefore(t, 'a', "bcdefghijk", strings.Join(visitorData.visited, ""))
+	assertOccursBefore(t, 'b', "ef", strings.Join(visitorData.visited, ""))
+	assertOccursBefore(t, 'c', "g", strings.Join(visitorData.visited, ""))
+	assertOccursBefore(t, 'e', "f", strings.Join(visitorData.visited, ""))
+	assertOccursBefore(t, 'h', "ijk", strings.Join(visitorData.visited, ""))
+	assertOccursBefore(t, 'i', "jk", strings.Join(visitorData.visited, ""))
+}
+
+func TestGraph_Start_lazyLaunch_error(t *testing.T) {
+	g := NewGraph()
+	g.AddActionE("a", failingAction("a"))
+	g.AddAction("b", visitorAction("b"))
+	g.LinkDependency("a", "b")
+
+	stats := NewStatistics()
+	r, err := g.Start(testContext(), newVisitordata(), WithLazyLaunch(), WithRecorders(stats.Recorder()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.EqualError(t, r.Wait(), `action "a": failed a`)
+	assert.True(t, stats.Executed("a"))
+	assert.False(t, stats.Executed("b"))
+	assert.Equal(t, stringSet("a", "b"), stats.Names())
+}
+
+func TestGraph_resolve_lazyLaunch_cycle(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", visitorAction("a"))
+	g.AddAction("b", visitorAction("b"))
+	g.AddAction("c", visitorAction("c"))
+	g.LinkDependency("a", "b")
+	g.LinkDependency("b", "c")
+	g.LinkDependency("b", "a")
+
+	s, err := g.resolve(testContext(), newVisitordata(), newResolveConfig([]ResolveOption{WithLazyLaunch()}))
+
+	assert.Equal(t, ErrCycle, err)
+	select {
+	case <-s.finished:
+	case <-time.After(testTimeout):
+		t.Fatal("parked actions were never launched")
+	}
+}
+
+func TestGraph_ResolveWith_allowEmpty(t *testing.T) {
+	g := NewGraph()
+
+	ctx, err := g.ResolveWith(testContext(), newVisitordata(), AllowEmpty())
+
+	assert.NoError(t, err)
+	select {
+	case <-ctx.Done():
+	default:
+		t.Fatal("context is not done")
+	}
+}
+
+func TestGraph_ResolveWith_allowEmpty_noRoots(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", visitorAction("a"))
+	g.AddAction("b", visitorAction("b"))
+	g.LinkDependency("a", "b")
+	g.LinkDependency("b", "a")
+
+	visitorData := newVisitordata()
+	r, err := g.Start(testContext(), visitorData, AllowEmpty())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, r.Wait())
+	assert.Empty(t, visitorData.visited)
+}
+
+func TestGraph_ResolveWith_empty(t *testing.T) {
+	g := NewGraph()
+
+	_, err := g.ResolveWith(testContext(), newVisitordata())
+
+	assert.Equal(t, ErrNoRoots, err)
+}
+
+func TestGraph_Start_actionTimeout(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", blockingAction(nil))
+	g.AddAction("b", visitorAction("b"))
+	g.LinkDependency("a", "b")
+
+	visitorData := newVisitordata()
+	r, err := g.Start(testContext(), visitorData, WithActionTimeout("a", time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Wait()
+
+	assert.EqualError(t, err, `action "a": context deadline exceeded`)
+	assert.Empty(t, visitorData.visited)
+}
+
+func TestGraph_ResolveStreamWith_actionTimeout_continueOnError(t *testing.T) {
+	g := NewGraph()
+	g.AddActionContinueOnError("a", func(ctx context.Context, arg interface{}) error {
+		<-ctx.Done()
+		return nil
+	})
+	g.AddAction("b", visitorAction("b"))
+	g.AddAction("c", visitorAction("c"))
+	g.AddAction("d", visitorAction("d"))
+	g.LinkDependency("a", "b")
+	g.LinkDependency("b", "c")
+
+	visitorData := newVisitordata()
+	events, err := g.ResolveStreamWith(testContext(), visitorData, WithActionTimeout("a", time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := collectEvents(events)
+
+	assert.Equal(t, []string{"d"}, visitorData.visited)
+	i := indexOfEvent(out, "a", EventSwallow)
+	if assert.NotEqual(t, -1, i) {
+		assert.Equal(t, context.DeadlineExceeded, out[i].Err)
+	}
+	assert.NotEqual(t, -1, indexOfEvent(out, "a", EventTimeout))
+	assert.NotEqual(t, -1, indexOfEvent(out, "b", EventAbort))
+	assert.NotEqual(t, -1, indexOfEvent(out, "c", EventAbort))
+	assert.Equal(t, -1, indexOfEvent(out, "b", EventStart))
+}
+
+func TestGraph_ResolveStreamWith_failedAbortsDependents(t *testing.T) {
+	g := NewGraph()
+	g.AddActionE("a", failingAction("a"))
+	g.AddAction("b", visitorAction("b"))
+	g.LinkDependency("a", "b")
+
+	visitorData := newVisitordata()
+	events, err := g.ResolveStreamWith(testContext(), visitorData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := collectEvents(events)
+
+	assert.Equal(t, []string{"a"}, visitorData.visited)
+	assert.NotEqual(t, -1, indexOfEvent(out, "a", EventError))
+	assert.NotEqual(t, -1, indexOfEvent(out, "b", EventAbort))
+}
+
+func TestStatistics_TimedOut(t *testing.T) {
+	g := NewGraph()
+	g.AddActionContinueOnError("a", func(ctx context.Context, arg interface{}) error {
+		<-ctx.Done()
+		return nil
+	})
+	g.AddAction("b", visitorAction("b"))
+	g.AddAction("c", visitorAction("c"))
+	g.LinkDependency("a", "b")
+	stats := NewStatistics()
+
+	r, err := g.Start(testContext(), newVisitordata(), WithRecorders(stats.Recorder()), WithActionTimeout("a", time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Wait()
+
+	assert.Equal(t, []string{"a"}, stats.TimedOut())
+}
+
+func TestStatistics_TimedOut_resolveTimeout(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", blockingAction(nil))
+	stats := NewStatistics()
+
+	r, err := g.Start(testContext(), newVisitordata(), WithRecorders(stats.Recorder()),
+		WithTimeout(time.Millisecond), WithActionTimeout("a", time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, context.DeadlineExceeded, r.Wait())
+	assert.Empty(t, stats.TimedOut())
+}
+
+// queueExecutor is an executor that runs tasks in order on a single worker
+type queueExecutor struct {
+	tasks chan func()
+	ran   int64
+}
+
+func newQueueExecutor() *queueExecutor {
+	e := &queueExecutor{tasks: make(chan func(), 1024)}
+	go func() {
+		for task := range e.tasks {
+			atomic.AddInt64(&e.ran, 1)
+			task()
+		}
+	}()
+	return e
+}
+
+func (e *queueExecutor) submit(task func()) {
+	e.tasks <- task
+}
+
+func TestGraph_ResolveWith_executor(t *testing.T) {
+	for _, lazy := range []bool{false, true} {
+		g := definedGraph(t)
+		executor := newQueueExecutor()
+		opts := []ResolveOption{WithExecutor(executor.submit)}
+		if lazy {
+			opts = append(opts, WithLazyLaunch())
+		}
+
+		visitorData := newVisitordata()
+		ctx, err := g.ResolveWith(testContext(), visitorData, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		<-ctx.Done()
+		close(executor.tasks)
+
+		assert.Len(t, visitorData.visited, 11, "lazy=%v", lazy)
+		assert.Equal(t, int64(11), atomic.LoadInt64(&executor.ran), "lazy=%v", lazy)
+		assertOccursBefore(t, 'a', "bcdefghijk", strings.Join(visitorData.visited, ""))
+	}
+}
+
+func TestGraph_ResolveResume(t *testing.T) {
+	var fixed int32
+	g := NewGraph()
+	g.AddAction("a", visitorAction("a"))
+	g.AddActionE("b", func(ctx context.Context, arg interface{}) error {
+		if atomic.LoadInt32(&fixed) == 0 {
+			return failingAction("b")(ctx, arg)
+		}
+		return nil
+	})
+	g.AddAction("c", visitorAction("c"))
+	g.LinkDependency("a", "b")
+	g.LinkDependency("b", "c")
+
+	prior := NewStatistics()
+	r, err := g.Start(testContext(), newVisitordata(), WithRecorders(prior.Recorder()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.EqualError(t, r.Wait(), `action "b": failed b`)
+	assert.EqualError(t, prior.Err("b"), "failed b")
+
+	atomic.StoreInt32(&fixed, 1)
+	visitorData := newVisitordata()
+	stats := NewStatistics()
+	ctx, err := g.ResolveResume(testContext(), visitorData, prior, WithRecorders(stats.Recorder()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-ctx.Done()
+
+	assert.Equal(t, []string{"c"}, visitorData.visited)
+	assert.True(t, stats.Executed("b"))
+	assert.False(t, stats.Executed("a"))
+	assert.Equal(t, stringSet("b", "c"), stats.Names())
+}
+
+func TestGraph_ResolveResume_nil(t *testing.T) {
+	g := definedGraph(t)
+	visitorData := newVisitordata()
+
+	ctx, err := g.ResolveResume(testContext(), visitorData, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-ctx.Done()
+
+	assert.Len(t, visitorData.visited, 11)
+}
+
+func TestWithResume_readsPriorAsResolveBegins(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", visitorAction("a"))
+	g.AddAction("b", visitorAction("b"))
+	g.LinkDependency("a", "b")
+	prior := NewStatistics()
+	resume := WithResume(prior)
+
+	r, err := g.Start(testContext(), newVisitordata(), WithRecorders(prior.Recorder()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, r.Wait())
+	visitorData := newVisitordata()
+	r, err = g.Start(testContext(), visitorData, resume)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, r.Wait())
+
+	assert.Empty(t, visitorData.visited)
+}
+
+func enteredWithSeed(t *testing.T, g *Graph, seed int64) []string {
+	recorder := &enterRecorder{mx: &sync.Mutex{}}
+	ctx, err := g.ResolveWith(testContext(), newVisitordata(), WithSchedulerSeed(seed), WithRecorders(recorder))
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-ctx.Done()
+	return recorder.entered
+}
+
+func TestGraph_ResolveWith_schedulerSeed(t *testing.T) {
+	g := definedGraph(t)
+
+	first := enteredWithSeed(t, g, 1)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, first, enteredWithSeed(t, g, 1))
+	}
+	assert.Len(t, first, 11)
+
+	differs := false
+	for seed := int64(2); seed < 10 && !differs; seed++ {
+		differs = !assert.ObjectsAreEqual(first, enteredWithSeed(t, g, seed))
+	}
+	assert.True(t, differs, "every seed entered actions in the same order")
+}
+
+func deadBranchGraph() *Graph {
+	g := NewGraph()
+	g.AddAction("a", visitorAction("a"))
+	g.AddAction("b", visitorAction("b"))
+	g.AddAction("c", visitorAction("c"))
+	g.AddAction("d", visitorAction("d"))
+	g.LinkDependency("a", "b")
+	g.LinkDependency("b", "c")
+	g.LinkDependency("c", "b")
+	g.LinkDependency("a", "d")
+	return g
+}
+
+func TestGraph_ResolveWith_deadBranches(t *testing.T) {
+	g := deadBranchGraph()
+	visitorData := newVisitordata()
+
+	ctx, err := g.ResolveWith(testContext(), visitorData)
+	<-ctx.Done()
+
+	assert.EqualError(t, err, "dead branches can never run: b, c")
+	assert.Empty(t, visitorData.visited)
+}
+
+func TestGraph_ResolveWith_pruneDeadBranches(t *testing.T) {
+	g := deadBranchGraph()
+	visitorData := newVisitordata()
+
+	r, err := g.Start(testContext(), visitorData, PruneDeadBranches())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, r.Wait())
+	assert.Equal(t, []string{"a", "d"}, visitorData.visited)
+}
+
+func TestGraph_ResolveWith_gates(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", visitorAction("a"))
+	g.AddAction("b", visitorAction("b"))
+	g.AddAction("c", visitorAction("c"))
+	g.AddAction("d", visitorAction("d"))
+	g.LinkDependency("a", "b")
+	g.LinkDependency("b", "c")
+	g.LinkDependency("a", "d")
+	visitorData := newVisitordata()
+	log, recorder := NewEventLog()
+	open := GateFunc(func(name string) bool { return true })
+	breaker := GateFunc(func(name string) bool { return name != "b" })
+
+	r, err := g.Start(testContext(), visitorData, WithGates(open, breaker), WithRecorders(recorder))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, r.Wait())
+	assert.ElementsMatch(t, []string{"a", "d"}, visitorData.visited)
+	assert.True(t, log.Index("b", EventGate) < log.Index("b", EventSkip))
+	assert.Equal(t, -1, log.Index("c", EventGate))
+	assert.NotEqual(t, -1, log.Index("c", EventSkip))
+	assert.Equal(t, StatusSkipped, r.Status()["b"])
+	assert.Equal(t, StatusSkipped, r.Status()["c"])
+}
+
+// flakyAction fails until it has been executed succeedOn times
+func flakyAction(name string, succeedOn int) ActionE {
+	attempts := 0
+	return func(ctx context.Context, arg interface{}) error {
+		attempts++
+		if attempts < succeedOn {
+			return errors.Errorf("%s attempt %d failed", name, attempts)
+		}
+		arg.(*visitordata).Visit(name)
+		return nil
+	}
+}
+
+func TestGraph_ResolveWith_retry(t *testing.T) {
+	g := NewGraph()
+	g.AddActionE("a", flakyAction("a", 3))
+	visitorData := newVisitordata()
+	log, recorder := NewEventLog()
+
+	r, err := g.Start(testContext(), visitorData, WithRetry("a", RetryPolicy{Attempts: 3}), WithRecorders(recorder))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, r.Wait())
+	assert.Equal(t, []string{"a"}, visitorData.visited)
+	var retries []int
+	for _, e := range log.Events() {
+		if e.Kind == EventRetry {
+			retries = append(retries, e.Attempt)
+		}
+	}
+	assert.Equal(t, []int{2, 3}, retries)
+	assert.Equal(t, -1, log.Index("a", EventGiveUp))
+}
+
+func TestGraph_ResolveWith_retryAttemptsExhausted(t *testing.T) {
+	g := NewGraph()
+	g.AddActionE("a", flakyAction("a", 4))
+	log, recorder := NewEventLog()
+
+	r, err := g.Start(testContext(), newVisitordata(), WithRetry("a", RetryPolicy{Attempts: 3, Budget: time.Hour}), WithRecorders(recorder))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.EqualError(t, r.Wait(), `action "a": a attempt 3 failed`)
+	assert.Equal(t, ErrAttemptsExhausted, log.Events()[log.Index("a", EventGiveUp)].Err)
+}
+
+func TestGraph_ResolveWith_retryBudgetExhausted(t *testing.T) {
+	g := NewGraph()
+	g.AddActionE("a", func(ctx context.Context, arg interface{}) error {
+		time.Sleep(5 * time.Millisecond)
+		return errors.New("slow failure")
+	})
+	log, recorder := NewEventLog()
+
+	r, err := g.Start(testContext(), nil, WithRetry("a", RetryPolicy{Attempts: 100, Budget: 12 * time.Millisecond}), WithRecorders(recorder))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.EqualError(t, r.Wait(), `action "a": slow failure`)
+	assert.Equal(t, ErrBudgetExhausted, log.Events()[log.Index("a", EventGiveUp)].Err)
+}
+
+func TestWithRetry_unbounded(t *testing.T) {
+	cfg := newResolveConfig([]ResolveOption{WithRetry("a", RetryPolicy{})})
+
+	assert.Empty(t, cfg.retries)
+}
+
+// finishHook is a Recorder that calls onFinish when an Action finishes
+type finishHook struct {
+	BaseRecorder
+	onFinish func(name string)
+}
+
+func (h *finishHook) Finish(name string) {
+	h.onFinish(name)
+}
+
+func TestGraph_Start_untilFinished(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	g := NewGraph()
+	g.AddAction("a", func(ctx context.Context, arg interface{}) {
+		<-started
+		arg.(*visitordata).Visit("a")
+	})
+	g.AddAction("b", visitorAction("b"))
+	g.AddAction("c", func(ctx context.Context, arg interface{}) {
+		close(started)
+		<-release
+		arg.(*visitordata).Visit("c")
+	})
+	g.LinkDependency("a", "b")
+	hook := &finishHook{onFinish: func(name string) {
+		if name == "a" {
+			close(release)
+		}
+	}}
+	log, recorder := NewEventLog()
+
+	visitorData := newVisitordata()
+	r, err := g.Start(testContext(), visitorData, WithRecorders(hook, recorder), UntilFinished("a", false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, r.Wait())
+	assert.ElementsMatch(t, []string{"a", "c"}, visitorData.visited)
+	assert.NotEqual(t, -1, log.Index("b", EventSkip))
+	assert.Equal(t, -1, log.Index("b", EventStart))
+	assert.Equal(t, StatusFinished, r.Status()["c"])
+}
+
+func TestGraph_Start_untilFinished_cancelRunning(t *testing.T) {
+	started := make(chan struct{})
+	g := NewGraph()
+	g.AddAction("a", func(ctx context.Context, arg interface{}) {
+		<-started
+		arg.(*visitordata).Visit("a")
+	})
+	g.AddAction("b", visitorAction("b"))
+	g.AddAction("c", func(ctx context.Context, arg interface{}) {
+		close(started)
+		<-ctx.Done()
+	})
+	g.AddAction("d", visitorAction("d"))
+	g.LinkDependency("a", "b")
+	g.LinkDependency("c", "d")
+	log, recorder := NewEventLog()
+
+	visitorData := newVisitordata()
+	r, err := g.Start(testContext(), visitorData, WithRecorders(recorder), UntilFinished("a", true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, r.Wait())
+	assert.Equal(t, []string{"a"}, visitorData.visited)
+	assert.NotEqual(t, -1, log.Index("b", EventSkip))
+	assert.NotEqual(t, -1, log.Index("d", EventSkip))
+	assert.Equal(t, -1, log.Index("c", EventFinish))
+	assert.Equal(t, StatusAborted, r.Status()["c"])
+}
+
+func TestGraph_ResolveWith_overrides(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", visitorAction("a"))
+	g.AddAction("b", visitorAction("b"))
+	g.LinkDependency("a", "b")
+	overrides := map[string]Action{
+		"a":       visitorAction("fake a"),
+		"missing": visitorAction("missing"),
+		"b":       nil,
+	}
+
+	visitorData := newVisitordata()
+	r, err := g.Start(testContext(), visitorData, WithOverrides(overrides))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, r.Wait())
+	assert.Equal(t, []string{"fake a", "b"}, visitorData.visited)
+
+	visitorData = newVisitordata()
+	r, err = g.Start(testContext(), visitorData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, r.Wait())
+	assert.Equal(t, []string{"a", "b"}, visitorData.visited)
+}
+
+func TestGraph_ResolveWith_beforeAfterEach(t *testing.T) {
+	mx := &sync.Mutex{}
+	var calls []string
+	call := func(format string, args ...interface{}) {
+		mx.Lock()
+		calls = append(calls, fmt.Sprintf(format, args...))
+		mx.Unlock()
+	}
+	g := NewGraph()
+	g.AddAction("a", func(ctx context.Context, arg interface{}) {
+		call("a")
+	})
+	g.AddActionE("b", func(ctx context.Context, arg interface{}) error {
+		call("b")
+		return ErrNoChange
+	})
+	g.AddAction("c", sampleaction)
+	g.LinkChain("a", "b")
+	before := func(label string) func(ctx context.Context, name string) {
+		return func(ctx context.Context, name string) {
+			node, _ := NodeFromContext(ctx)
+			call("%s before %s %v", label, name, node != nil)
+		}
+	}
+	after := func(label string) func(ctx context.Context, name string, err error) {
+		return func(ctx context.Context, name string, err error) {
+			call("%s after %s %v", label, name, err)
+		}
+	}
+
+	r, err := g.Start(testContext(), nil,
+		WithSkip(skipNames("c")),
+		WithBeforeEach(before("outer")), WithAfterEach(after("outer")),
+		WithBeforeEach(before("inner")), WithAfterEach(after("inner")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, r.Wait())
+	assert.Equal(t, []string{
+		"outer before a true",
+		"inner before a true",
+		"a",
+		"inner after a <nil>",
+		"outer after a <nil>",
+		"outer before b true",
+		"inner before b true",
+		"b",
+		"inner after b no change",
+		"outer after b no change",
+	}, calls)
+}
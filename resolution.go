@@ -0,0 +1,335 @@
+package depfunc
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Resolution is a handle to a resolve of a Graph that is in progress.
+// It gathers the context, cancellation and outcome of the resolve in one place:
+// Wait blocks until the resolve is done and returns why it failed, if it did.
+type Resolution struct {
+	g        *Graph
+	s        search
+	progress *statusRecorder
+}
+
+// Start begins resolving this Graph on a given context, configured by opts,
+// and returns a handle to inspect the resolve while it runs.
+func (g *Graph) Start(ctx context.Context, arg interface{}, opts ...ResolveOption) (*Resolution, error) {
+	progress := newStatusRecorder()
+	s, err := g.resolve(ctx, arg, newResolveConfig(append(opts, WithRecorders(progress))))
+	if err != nil {
+		return nil, err
+	}
+	return &Resolution{g: s.graph, s: s, progress: progress}, nil
+}
+
+// ResolveWithStats executes this Graph on a given context, configured by opts,
+// recorded by a new Statistics, and blocks until every Action has exited.
+// It returns the Statistics, which are then final, along with the error that
+// ended the resolve early, if any, like Wait. The Statistics are returned
+// even when the resolve fails, to show how far it got.
+func (g *Graph) ResolveWithStats(ctx context.Context, arg interface{}, opts ...ResolveOption) (*Statistics, error) {
+	stats := NewStatistics()
+	r, err := g.Start(ctx, arg, append(opts, WithRecorders(stats.Recorder()))...)
+	if err != nil {
+		return stats, err
+	}
+	return stats, r.Wait()
+}
+
+// Context returns the context the Actions are executed in.
+// It is done when the Actions are all executed or an error occurs.
+func (r *Resolution) Context() context.Context {
+	return r.s.ctx
+}
+
+// Done returns a channel that is closed once every Action has exited
+func (r *Resolution) Done() <-chan struct{} {
+	return r.s.finished
+}
+
+// Elapsed returns how long the resolve took, from when it began to when every
+// Action had exited, or how long it has taken so far if it is not yet Done
+func (r *Resolution) Elapsed() time.Duration {
+	select {
+	case <-r.s.finished:
+		return r.s.ended.Sub(r.s.begun)
+	default:
+		return time.Since(r.s.begun)
+	}
+}
+
+// Wait blocks until every Action has exited and returns Err
+func (r *Resolution) Wait() error {
+	<-r.s.finished
+	return r.Err()
+}
+
+// Cancel aborts the resolve. Once Cancel returns, no further Actions will start.
+// Actions that are executing are expected to observe the cancellation of their context.
+func (r *Resolution) Cancel() {
+	r.s.abort()
+}
+
+// CancelNode cancels the Action name without cancelling the resolve.
+// If it is executing, its context is cancelled, it is reported as exited
+// without finishing whatever it returns, and it does not fail the resolve.
+// If it has not started, it is skipped instead of executing.
+// Either way, every Action that depends on it, directly or transitively, is
+// skipped, even one that other Actions still need: it cannot be given what it
+// depends on. Actions that do not depend on it are unaffected, as are Actions
+// added by Node.AddDependent. CancelNode does nothing if name has already exited.
+func (r *Resolution) CancelNode(name string) {
+	if r.s.completed.Contains(name) {
+		return
+	}
+	r.s.nodes.cancel(name)
+}
+
+// Pause holds back Actions from starting until Resume is called, for
+// debugging a resolve interactively, for instance. It is best-effort: Actions
+// that are executing carry on, as do their timeouts and the resolve's deadline,
+// and only those that have yet to start wait, once their dependencies have
+// finished. Those that wait do so before they are handed to the executor, so
+// they do not hold slots of a Scheduler shared with other resolves. Cancelling
+// the resolve releases the Actions that are waiting.
+func (r *Resolution) Pause() {
+	r.s.pause.set(true)
+}
+
+// Resume lets the Actions held back by Pause start
+func (r *Resolution) Resume() {
+	r.s.pause.set(false)
+}
+
+// Paused returns if the resolve is paused
+func (r *Resolution) Paused() bool {
+	return r.s.pause.isPaused()
+}
+
+// Err returns the error that ended the resolve early: the error of the Action
+// that failed, context.DeadlineExceeded if the resolve's deadline passed, or
+// context.Canceled if it was otherwise cancelled. It returns nil if the resolve
+// succeeded or, until it is Done, if no Action has failed yet.
+func (r *Resolution) Err() error {
+	return r.s.outcome.get()
+}
+
+// Succeeded returns if the resolve is Done and every Action finished without
+// ending it early. It returns false while the resolve is still running.
+func (r *Resolution) Succeeded() bool {
+	select {
+	case <-r.s.finished:
+		return r.Err() == nil
+	default:
+		return false
+	}
+}
+
+// Waiting returns each Action that has not started, mapped to the
+// sorted names of the dependencies it is still waiting on.
+// An Action with no unfinished dependencies is waiting to be scheduled.
+func (r *Resolution) Waiting() map[string][]string {
+	waiting := make(map[string][]string)
+	for name := range r.s.visited {
+		if r.s.skipped.Contains(name) || r.s.started.Contains(name) || r.s.completed.Contains(name) {
+			continue
+		}
+		deps := []string{}
+		for dep := range r.g.treeOrder[name] {
+			if !r.s.completed.Contains(dep) && !r.s.resumed.Contains(dep) {
+				deps = append(deps, dep)
+			}
+		}
+		sort.Strings(deps)
+		waiting[name] = deps
+	}
+	return waiting
+}
+
+// NotStarted returns the sorted names of the Actions that have not begun
+// executing, including those never entered because the resolve was cancelled
+// before it reached them, such as all of them if it was cancelled before it
+// began. Once the resolve is Done, these are what remains to be done, with
+// ResolveResume, for instance. Actions left out of the resolve by WithSkip
+// or WithTagFilter, and those that finished in a prior resolve, are not included.
+func (r *Resolution) NotStarted() []string {
+	var names []string
+	for name := range r.g.actions {
+		if !r.s.skipped.Contains(name) && !r.s.resumed.Contains(name) && !r.s.started.Contains(name) {
+			names = append(names, name)
+		}
+	}
+	for name := range r.s.added.names() {
+		if !r.s.started.Contains(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NodeStatus is the progress of an Action in a resolve
+type NodeStatus int
+
+const (
+	// StatusPending is when an Action is part of the resolve but has not been entered
+	StatusPending NodeStatus = iota
+
+	// StatusWaiting is when an Action is waiting for its dependencies
+	StatusWaiting
+
+	// StatusRunning is when an Action is executing
+	StatusRunning
+
+	// StatusFinished is when an Action has finished executing
+	StatusFinished
+
+	// StatusAborted is when an Action exited without executing, or without
+	// finishing, because the resolve was cancelled or a dependency failed
+	StatusAborted
+
+	// StatusSkipped is when an Action will not be executed because it or a dependency was skipped
+	StatusSkipped
+)
+
+var nodeStatusNames = map[NodeStatus]string{
+	StatusPending:  "pending",
+	StatusWaiting:  "waiting",
+	StatusRunning:  "running",
+	StatusFinished: "finished",
+	StatusAborted:  "aborted",
+	StatusSkipped:  "skipped",
+}
+
+func (st NodeStatus) String() string {
+	if name, ok := nodeStatusNames[st]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// Status returns a snapshot of the progress of every Action in the resolve.
+// It is safe to call at any time while the resolve runs.
+func (r *Resolution) Status() map[string]NodeStatus {
+	status := r.progress.snapshot()
+	for name := range r.s.visited {
+		if _, ok := status[name]; !ok {
+			status[name] = StatusPending
+		}
+	}
+	return status
+}
+
+// statusRecorder is a Recorder that tracks the NodeStatus of each Action
+type statusRecorder struct {
+	mx     *sync.Mutex
+	status map[string]NodeStatus
+}
+
+func newStatusRecorder() *statusRecorder {
+	return &statusRecorder{
+		mx:     &sync.Mutex{},
+		status: make(map[string]NodeStatus),
+	}
+}
+
+func (p *statusRecorder) set(name string, status NodeStatus) {
+	p.mx.Lock()
+	p.status[name] = status
+	p.mx.Unlock()
+}
+
+func (p *statusRecorder) snapshot() map[string]NodeStatus {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	status := make(map[string]NodeStatus, len(p.status))
+	for name, st := range p.status {
+		status[name] = st
+	}
+	return status
+}
+
+func (p *statusRecorder) Enter(name string) {
+	p.set(name, StatusWaiting)
+}
+
+func (p *statusRecorder) Start(name string) {
+	p.set(name, StatusRunning)
+}
+
+func (p *statusRecorder) Finish(name string) {
+	p.set(name, StatusFinished)
+}
+
+func (p *statusRecorder) Exit(name string) {
+	p.mx.Lock()
+	if st := p.status[name]; st != StatusFinished && st != StatusSkipped {
+		p.status[name] = StatusAborted
+	}
+	p.mx.Unlock()
+}
+
+func (p *statusRecorder) Skip(name string) {
+	p.set(name, StatusSkipped)
+}
+
+// pauseGate holds back actions from starting while it is paused
+type pauseGate struct {
+	mx   *sync.Mutex
+	cond *sync.Cond
+
+	// paused is set to 1 while paused, so that waiting while not paused does not lock
+	paused int32
+
+	// waiting is how many actions are being held back, guarded by mx
+	waiting int
+}
+
+func newPauseGate() *pauseGate {
+	mx := &sync.Mutex{}
+	return &pauseGate{mx: mx, cond: sync.NewCond(mx)}
+}
+
+// set pauses or resumes, waking up the waiting actions when resumed
+func (p *pauseGate) set(paused bool) {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	if paused {
+		atomic.StoreInt32(&p.paused, 1)
+		return
+	}
+	atomic.StoreInt32(&p.paused, 0)
+	p.cond.Broadcast()
+}
+
+func (p *pauseGate) isPaused() bool {
+	return atomic.LoadInt32(&p.paused) == 1
+}
+
+// wait blocks while paused, until resumed or ctx is done
+func (p *pauseGate) wait(ctx context.Context) {
+	if !p.isPaused() {
+		return
+	}
+	stop := context.AfterFunc(ctx, func() {
+		p.mx.Lock()
+		p.cond.Broadcast()
+		p.mx.Unlock()
+	})
+	defer stop()
+	p.mx.Lock()
+	p.waiting++
+	// Wake anything waiting for the action to be held back
+	p.cond.Broadcast()
+	for p.isPaused() && ctx.Err() == nil {
+		p.cond.Wait()
+	}
+	p.waiting--
+	p.mx.Unlock()
+}
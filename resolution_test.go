@@ -0,0 +1,543 @@
+package depfunc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func blockingAction(release <-chan struct{}) Action {
+	return func(ctx context.Context, arg interface{}) {
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+	}
+}
+
+func TestGraph_Start(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", visitorAction("a"))
+	g.AddAction("b", visitorAction("b"))
+	g.LinkDependency("a", "b")
+	visitorData := newVisitordata()
+
+	r, err := g.Start(testContext(), visitorData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-r.Done()
+
+	assert.Equal(t, []string{"a", "b"}, visitorData.visited)
+	<-r.Context().Done()
+}
+
+func TestGraph_Start_error(t *testing.T) {
+	g := NewGraph()
+
+	r, err := g.Start(testContext(), nil)
+
+	assert.Equal(t, ErrNoRoots, err)
+	assert.Nil(t, r)
+}
+
+func TestResolution_Waiting(t *testing.T) {
+	release := make(chan struct{})
+	g := NewGraph()
+	g.AddAction("a", blockingAction(release))
+	g.AddAction("b", sampleaction)
+	g.AddAction("c", sampleaction)
+	g.AddAction("d", sampleaction)
+	g.LinkDependency("a", "c")
+	g.LinkDependency("b", "c")
+	g.LinkDependency("c", "d")
+
+	r, err := g.Start(testContext(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// wait for b to finish and a to block
+	for !r.s.completed.Contains("b") || !r.s.started.Contains("a") {
+		select {
+		case <-r.Done():
+			t.Fatal("resolve finished early")
+		default:
+		}
+	}
+
+	waiting := r.Waiting()
+	close(release)
+	<-r.Done()
+
+	assert.Equal(t, map[string][]string{
+		"c": {"a"},
+		"d": {"c"},
+	}, waiting)
+	assert.Len(t, r.Waiting(), 0)
+}
+
+func TestNodeStatus_String(t *testing.T) {
+	assert.Equal(t, "running", StatusRunning.String())
+	assert.Equal(t, "unknown", NodeStatus(-1).String())
+}
+
+func TestResolution_Status(t *testing.T) {
+	release := make(chan struct{})
+	g := NewGraph()
+	g.AddAction("a", blockingAction(release))
+	g.AddAction("b", sampleaction)
+	g.AddAction("c", sampleaction)
+	g.AddAction("d", sampleaction)
+	g.LinkDependency("a", "c")
+	g.LinkDependency("b", "c")
+
+	r, err := g.Start(testContext(), nil, WithSkip(skipNames("d")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		status := r.Status()
+		if status["a"] == StatusRunning && status["b"] == StatusFinished {
+			assert.Equal(t, StatusWaiting, status["c"])
+			assert.Equal(t, StatusSkipped, status["d"])
+			break
+		}
+	}
+	close(release)
+	<-r.Done()
+
+	assert.Equal(t, map[string]NodeStatus{
+		"a": StatusFinished,
+		"b": StatusFinished,
+		"c": StatusFinished,
+		"d": StatusSkipped,
+	}, r.Status())
+}
+
+func TestResolution_Status_aborted(t *testing.T) {
+	g := NewGraph()
+	g.AddActionE("a", failingAction("a"))
+	g.AddAction("b", sampleaction)
+	g.LinkDependency("a", "b")
+
+	r, err := g.Start(testContext(), newVisitordata())
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-r.Done()
+
+	assert.Equal(t, map[string]NodeStatus{
+		"a": StatusFinished,
+		"b": StatusAborted,
+	}, r.Status())
+}
+
+// startedAction returns an action that signals started once it is executing,
+// then blocks until release or its context is done
+func startedAction(started chan<- struct{}, release <-chan struct{}) Action {
+	return func(ctx context.Context, arg interface{}) {
+		started <- struct{}{}
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+	}
+}
+
+// awaitHeld blocks until n actions are held back by the gate
+func (p *pauseGate) awaitHeld(n int) {
+	p.mx.Lock()
+	for p.waiting < n {
+		p.cond.Wait()
+	}
+	p.mx.Unlock()
+}
+
+func TestResolution_Pause(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	g := NewGraph()
+	g.AddAction("a", startedAction(started, release))
+	g.AddAction("b", sampleaction)
+	g.LinkDependency("a", "b")
+
+	r, err := g.Start(testContext(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	assert.Equal(t, StatusRunning, r.Status()["a"])
+	r.Pause()
+	close(release)
+	r.s.pause.awaitHeld(1)
+
+	assert.True(t, r.Paused())
+	assert.Equal(t, StatusFinished, r.Status()["a"])
+	assert.Equal(t, StatusWaiting, r.Status()["b"])
+	r.Resume()
+	<-r.Done()
+	assert.False(t, r.Paused())
+	assert.Equal(t, StatusFinished, r.Status()["b"])
+}
+
+func TestResolution_Pause_scheduler(t *testing.T) {
+	scheduler := NewScheduler(2)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	paused := NewGraph()
+	paused.AddAction("a", startedAction(started, release))
+	paused.AddAction("b", sampleaction)
+	paused.AddAction("c", sampleaction)
+	paused.LinkFanOut("a", "b", "c")
+	other := NewGraph()
+	other.AddAction("d", sampleaction)
+
+	for _, lazy := range []bool{false, true} {
+		opts := []ResolveOption{WithScheduler(scheduler)}
+		if lazy {
+			opts = append(opts, WithLazyLaunch())
+		}
+		r, err := paused.Start(testContext(), nil, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		<-started
+		r.Pause()
+		release <- struct{}{}
+		r.s.pause.awaitHeld(2)
+
+		// b and c are held back without taking up the scheduler's slots
+		o, err := other.Start(testContext(), nil, WithScheduler(scheduler), WithLazyLaunch())
+		if err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-o.Done():
+		case <-time.After(time.Second):
+			t.Fatalf("resolve sharing the scheduler did not finish, lazy %v", lazy)
+		}
+		assert.Equal(t, StatusWaiting, r.Status()["b"], "lazy %v", lazy)
+		assert.Equal(t, StatusWaiting, r.Status()["c"], "lazy %v", lazy)
+		r.Resume()
+		assert.NoError(t, r.Wait())
+	}
+}
+
+func TestResolution_Pause_cancelled(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	g := NewGraph()
+	g.AddAction("a", startedAction(started, release))
+	g.AddAction("b", sampleaction)
+	g.LinkDependency("a", "b")
+
+	r, err := g.Start(testContext(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	r.Pause()
+	close(release)
+	r.s.pause.awaitHeld(1)
+	r.Cancel()
+	<-r.Done()
+
+	assert.Equal(t, StatusAborted, r.Status()["b"])
+}
+
+func TestResolution_Err(t *testing.T) {
+	g := definedGraph(t)
+
+	r, err := g.Start(testContext(), newVisitordata())
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-r.Done()
+
+	assert.NoError(t, r.Err())
+}
+
+func TestResolution_Err_action(t *testing.T) {
+	g := NewGraph()
+	g.AddActionE("a", failingAction("a"))
+
+	r, err := g.Start(testContext(), newVisitordata())
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-r.Done()
+
+	assert.EqualError(t, r.Err(), `action "a": failed a`)
+	assert.NotEqual(t, context.DeadlineExceeded, errors.Cause(r.Err()))
+}
+
+func TestResolution_Err_timeout(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", blockingAction(nil))
+
+	r, err := g.Start(testContext(), nil, WithTimeout(time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-r.Done()
+
+	assert.Equal(t, context.DeadlineExceeded, r.Err())
+}
+
+func TestResolution_Err_deadline(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", blockingAction(nil))
+
+	r, err := g.Start(testContext(), nil, WithDeadline(time.Now().Add(time.Millisecond)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-r.Done()
+
+	assert.Equal(t, context.DeadlineExceeded, r.Err())
+}
+
+func TestResolution_Err_cancelled(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", blockingAction(nil))
+	ctx, cancel := context.WithCancel(testContext())
+
+	r, err := g.Start(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	<-r.Done()
+
+	assert.Equal(t, context.Canceled, r.Err())
+}
+
+func TestResolution_Wait(t *testing.T) {
+	g := definedGraph(t)
+	visitorData := newVisitordata()
+
+	r, err := g.Start(testContext(), visitorData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Wait()
+
+	assert.NoError(t, err)
+	assert.Len(t, visitorData.visited, 11)
+}
+
+func TestResolution_Wait_error(t *testing.T) {
+	g := NewGraph()
+	g.AddActionE("a", failingAction("a"))
+
+	r, err := g.Start(testContext(), newVisitordata())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Wait()
+
+	assert.EqualError(t, err, `action "a": failed a`)
+}
+
+func TestGraph_ResolveWithStats(t *testing.T) {
+	g := definedGraph(t)
+	visitorData := newVisitordata()
+
+	stats, err := g.ResolveWithStats(testContext(), visitorData)
+
+	assert.NoError(t, err)
+	assert.Len(t, visitorData.visited, 11)
+	assert.Len(t, stats.Names(), 11)
+	assert.Equal(t, 11, stats.Summary().Executed)
+}
+
+func TestGraph_ResolveWithStats_error(t *testing.T) {
+	g := NewGraph()
+	g.AddActionE("a", failingAction("a"))
+	g.AddAction("b", sampleaction)
+	g.LinkDependency("a", "b")
+
+	stats, err := g.ResolveWithStats(testContext(), newVisitordata())
+
+	assert.EqualError(t, err, `action "a": failed a`)
+	assert.EqualError(t, stats.Err("a"), "failed a")
+	assert.True(t, stats.Executed("a"))
+	assert.False(t, stats.Executed("b"))
+}
+
+func TestResolution_Cancel(t *testing.T) {
+	release := make(chan struct{})
+	g := NewGraph()
+	g.AddAction("a", blockingAction(release))
+	g.AddAction("b", visitorAction("b"))
+	g.LinkDependency("a", "b")
+	visitorData := newVisitordata()
+
+	r, err := g.Start(testContext(), visitorData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Cancel()
+	err = r.Wait()
+	close(release)
+
+	assert.Equal(t, context.Canceled, err)
+	assert.Len(t, visitorData.visited, 0)
+}
+
+func TestResolution_NotStarted(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", visitorAction("a"))
+	g.AddActionE("b", failingAction("b"))
+	g.AddAction("c", visitorAction("c"))
+	g.AddAction("skipped", visitorAction("skipped"))
+	g.LinkDependency("a", "b")
+	g.LinkDependency("b", "c")
+
+	r, err := g.Start(testContext(), newVisitordata(), WithSkip(func(name string) bool { return name == "skipped" }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Wait()
+
+	assert.Equal(t, []string{"c"}, r.NotStarted())
+}
+
+func TestResolution_Succeeded(t *testing.T) {
+	release := make(chan struct{})
+	g := NewGraph()
+	g.AddAction("a", blockingAction(release))
+
+	r, err := g.Start(testContext(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, r.Succeeded())
+	close(release)
+	<-r.Done()
+
+	assert.True(t, r.Succeeded())
+}
+
+func TestResolution_Succeeded_error(t *testing.T) {
+	g := NewGraph()
+	g.AddActionE("a", failingAction("a"))
+
+	r, err := g.Start(testContext(), newVisitordata())
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-r.Done()
+
+	assert.False(t, r.Succeeded())
+}
+
+func TestResolution_CancelNode(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	g := NewGraph()
+	g.AddAction("a", func(ctx context.Context, arg interface{}) {
+		close(started)
+		<-ctx.Done()
+	})
+	g.AddAction("b", visitorAction("b"))
+	g.AddAction("c", blockingAction(release))
+	g.AddAction("d", visitorAction("d"))
+	g.LinkDependency("a", "b")
+	g.LinkDependency("c", "d")
+	visitorData := newVisitordata()
+
+	r, err := g.Start(testContext(), visitorData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	r.CancelNode("a")
+	close(release)
+
+	assert.NoError(t, r.Wait())
+	assert.Equal(t, []string{"d"}, visitorData.visited)
+	assert.Equal(t, map[string]NodeStatus{
+		"a": StatusAborted,
+		"b": StatusSkipped,
+		"c": StatusFinished,
+		"d": StatusFinished,
+	}, r.Status())
+}
+
+func TestResolution_CancelNode_notStarted(t *testing.T) {
+	release := make(chan struct{})
+	g := NewGraph()
+	g.AddAction("a", blockingAction(release))
+	g.AddAction("b", visitorAction("b"))
+	g.AddAction("c", visitorAction("c"))
+	g.LinkChain("a", "b", "c")
+	visitorData := newVisitordata()
+
+	r, err := g.Start(testContext(), visitorData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.CancelNode("b")
+	close(release)
+
+	assert.NoError(t, r.Wait())
+	assert.Empty(t, visitorData.visited)
+	assert.Equal(t, StatusFinished, r.Status()["a"])
+	assert.Equal(t, StatusSkipped, r.Status()["b"])
+	assert.Equal(t, StatusSkipped, r.Status()["c"])
+}
+
+func TestResolution_CancelNode_finished(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", sampleaction)
+
+	r, err := g.Start(testContext(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Wait()
+	r.CancelNode("a")
+
+	assert.True(t, r.Succeeded())
+	assert.Equal(t, StatusFinished, r.Status()["a"])
+}
+
+func TestResolution_Elapsed(t *testing.T) {
+	release := make(chan struct{})
+	g := NewGraph()
+	g.AddAction("a", blockingAction(release))
+
+	r, err := g.Start(testContext(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	running := r.Elapsed()
+	close(release)
+	r.Wait()
+	elapsed := r.Elapsed()
+
+	assert.True(t, running >= 5*time.Millisecond)
+	assert.True(t, elapsed >= running)
+	assert.Equal(t, elapsed, r.Elapsed())
+}
+
+func TestResolution_Elapsed_cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(testContext())
+	cancel()
+	g := NewGraph()
+	g.AddAction("a", sampleaction)
+
+	r, err := g.Start(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Wait()
+
+	assert.True(t, r.Elapsed() > 0)
+	assert.Equal(t, r.Elapsed(), r.Elapsed())
+}
@@ -0,0 +1,136 @@
+package depfunc
+
+import (
+	"context"
+	"sync"
+)
+
+// ResultAction is an ActionE that returns a result. The resolve keeps the result
+// of each ResultAction that does not fail under the action's name, so that data
+// flows out of actions without them writing to a shared arg.
+type ResultAction func(ctx context.Context, arg interface{}) (interface{}, error)
+
+// AddResultAction adds an action that returns a result to the graph.
+// Once the action has returned without failing, its result is available
+// from the Resolution of the resolve with Result.
+func (g *Graph) AddResultAction(name string, action ResultAction) error {
+	if name == "" {
+		return ErrEmptyName
+	}
+	if action == nil {
+		return ErrNilAction
+	}
+	return g.AddActionE(name, func(ctx context.Context, arg interface{}) error {
+		result, err := action(ctx, arg)
+		if !succeeded(err) {
+			return err
+		}
+		if node, ok := NodeFromContext(ctx); ok {
+			node.s.results.set(node.name, result)
+		}
+		return err
+	})
+}
+
+// DataflowAction is a ResultAction that is given the results of its dependencies
+// instead of an arg: inputs maps the name of each of its direct dependencies that
+// returned a result, such as another DataflowAction, to that result.
+type DataflowAction func(ctx context.Context, inputs map[string]interface{}) (interface{}, error)
+
+// AddDataflowAction adds an action that is given the results of its dependencies to the graph.
+// Its own result is kept like that of a ResultAction, for its dependents and the Resolution.
+func (g *Graph) AddDataflowAction(name string, action DataflowAction) error {
+	if name == "" {
+		return ErrEmptyName
+	}
+	if action == nil {
+		return ErrNilAction
+	}
+	return g.AddResultAction(name, func(ctx context.Context, arg interface{}) (interface{}, error) {
+		inputs := make(map[string]interface{})
+		if node, ok := NodeFromContext(ctx); ok {
+			inputs = node.s.results.collect(node.s.dependencies(node.g, node.name))
+		}
+		return action(ctx, inputs)
+	})
+}
+
+// Result returns the result of the ResultAction name,
+// or false if it has not returned one, such as if it failed or has not finished
+func (r *Resolution) Result(name string) (interface{}, bool) {
+	return r.s.results.get(name)
+}
+
+// ResolveTargetValue executes only the action target and what it depends on,
+// directly or transitively, and blocks until they have exited. It returns the
+// result of target, if it is a ResultAction, or the error that ended the resolve
+// early, such as the error of target or of one of its dependencies. The result is
+// nil if target is not a ResultAction. The other options, such as WithSkip, are
+// applied as well, so target may not execute at all.
+func (g *Graph) ResolveTargetValue(ctx context.Context, arg interface{}, target string, opts ...ResolveOption) (interface{}, error) {
+	// Plan and resolve the same snapshot, so that the graph changing in between does not matter
+	g = g.snapshot()
+	if _, exists := g.actions[target]; !exists {
+		return nil, &NameError{Name: target, Err: ErrUnknownAction}
+	}
+	dependencies, err := g.TransitiveDependencies(target)
+	if err != nil {
+		return nil, err
+	}
+	needed := make(StringSet, len(dependencies)+1)
+	needed.Add(target)
+	for _, dependency := range dependencies {
+		needed.Add(dependency)
+	}
+
+	r, err := g.Start(ctx, arg, append(opts, WithSkip(func(name string) bool {
+		return !needed.Contains(name)
+	}))...)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Wait(); err != nil {
+		return nil, err
+	}
+	result, _ := r.Result(target)
+	return result, nil
+}
+
+// resultMap holds the results of the actions of a resolve
+type resultMap struct {
+	mx      *sync.RWMutex
+	results map[string]interface{}
+}
+
+func newResultMap() *resultMap {
+	return &resultMap{
+		mx:      &sync.RWMutex{},
+		results: make(map[string]interface{}),
+	}
+}
+
+func (m *resultMap) set(name string, result interface{}) {
+	m.mx.Lock()
+	m.results[name] = result
+	m.mx.Unlock()
+}
+
+func (m *resultMap) get(name string) (interface{}, bool) {
+	m.mx.RLock()
+	defer m.mx.RUnlock()
+	result, ok := m.results[name]
+	return result, ok
+}
+
+// collect returns the results of names, for those that have one
+func (m *resultMap) collect(names StringSet) map[string]interface{} {
+	m.mx.RLock()
+	defer m.mx.RUnlock()
+	collected := make(map[string]interface{}, len(names))
+	for name := range names {
+		if result, ok := m.results[name]; ok {
+			collected[name] = result
+		}
+	}
+	return collected
+}
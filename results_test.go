@@ -0,0 +1,163 @@
+package depfunc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraph_AddResultAction(t *testing.T) {
+	g := NewGraph()
+	g.AddResultAction("apples", func(ctx context.Context, arg interface{}) (interface{}, error) {
+		return 3, nil
+	})
+	g.AddResultAction("cans", func(ctx context.Context, arg interface{}) (interface{}, error) {
+		return nil, errors.New("out of metal")
+	})
+	g.AddAction("qa", sampleaction)
+	g.LinkDependency("apples", "cans")
+
+	r, err := g.Start(testContext(), nil, WithRecorders(newErrorRecorder()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Wait()
+
+	apples, ok := r.Result("apples")
+	assert.True(t, ok)
+	assert.Equal(t, 3, apples)
+	_, ok = r.Result("cans")
+	assert.False(t, ok)
+	_, ok = r.Result("qa")
+	assert.False(t, ok)
+}
+
+func TestGraph_AddResultAction_skipDependents(t *testing.T) {
+	g := NewGraph()
+	g.AddResultAction("cache", func(ctx context.Context, arg interface{}) (interface{}, error) {
+		return "cached", ErrSkipDependents
+	})
+	g.AddAction("compute", visitorAction("compute"))
+	g.LinkDependency("cache", "compute")
+	visitorData := newVisitordata()
+
+	r, err := g.Start(testContext(), visitorData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, r.Wait())
+	result, ok := r.Result("cache")
+	assert.True(t, ok)
+	assert.Equal(t, "cached", result)
+	assert.Empty(t, visitorData.visited)
+}
+
+func TestGraph_AddDataflowAction(t *testing.T) {
+	g := NewGraph()
+	g.AddDataflowAction("apples", func(ctx context.Context, inputs map[string]interface{}) (interface{}, error) {
+		return 3, nil
+	})
+	g.AddDataflowAction("sugars", func(ctx context.Context, inputs map[string]interface{}) (interface{}, error) {
+		return 2, nil
+	})
+	g.AddAction("qa", sampleaction)
+	var got map[string]interface{}
+	g.AddDataflowAction("applesauce", func(ctx context.Context, inputs map[string]interface{}) (interface{}, error) {
+		got = inputs
+		return inputs["apples"].(int) + inputs["sugars"].(int), nil
+	})
+	g.LinkFanIn("applesauce", "apples", "sugars", "qa")
+
+	r, err := g.Start(testContext(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, r.Wait())
+	assert.Equal(t, map[string]interface{}{"apples": 3, "sugars": 2}, got)
+	applesauce, _ := r.Result("applesauce")
+	assert.Equal(t, 5, applesauce)
+}
+
+func TestGraph_AddDataflowAction_noChange(t *testing.T) {
+	g := NewGraph()
+	unchanged := func(ctx context.Context, inputs map[string]interface{}) (interface{}, error) {
+		return "prior", ErrNoChange
+	}
+	changed := func(ctx context.Context, inputs map[string]interface{}) (interface{}, error) {
+		return "new", nil
+	}
+	shortcut := func(ctx context.Context, inputs map[string]interface{}) (interface{}, error) {
+		node, _ := NodeFromContext(ctx)
+		if node.DependenciesUnchanged() {
+			return inputs, ErrNoChange
+		}
+		return inputs, nil
+	}
+	g.AddDataflowAction("a", unchanged)
+	g.AddDataflowAction("b", unchanged)
+	g.AddDataflowAction("c", changed)
+	g.AddDataflowAction("ab", shortcut)
+	g.AddDataflowAction("ac", shortcut)
+	g.LinkFanIn("ab", "a", "b")
+	g.LinkFanIn("ac", "a", "c")
+	log, recorder := NewEventLog()
+
+	r, err := g.Start(testContext(), nil, WithRecorders(recorder))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, r.Wait())
+	ab, _ := r.Result("ab")
+	assert.Equal(t, map[string]interface{}{"a": "prior", "b": "prior"}, ab)
+	assert.NotEqual(t, -1, log.Index("ab", EventNoChange))
+	assert.Equal(t, -1, log.Index("ac", EventNoChange))
+	assert.NotEqual(t, -1, log.Index("a", EventNoChange))
+	assert.Equal(t, -1, log.Index("c", EventNoChange))
+}
+
+func TestGraph_ResolveTargetValue(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("metals", visitorAction("metals"))
+	g.AddResultAction("cans", func(ctx context.Context, arg interface{}) (interface{}, error) {
+		visitorAction("cans")(ctx, arg)
+		return 12, nil
+	})
+	g.AddAction("apples", visitorAction("apples"))
+	g.AddAction("applesauce", visitorAction("applesauce"))
+	g.LinkChain("metals", "cans", "applesauce")
+	g.LinkDependency("apples", "applesauce")
+	visitorData := newVisitordata()
+
+	cans, err := g.ResolveTargetValue(testContext(), visitorData, "cans")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 12, cans)
+	assert.Equal(t, []string{"metals", "cans"}, visitorData.visited)
+}
+
+func TestGraph_ResolveTargetValue_errors(t *testing.T) {
+	g := NewGraph()
+	g.AddActionE("metals", failingAction("metals"))
+	g.AddResultAction("cans", func(ctx context.Context, arg interface{}) (interface{}, error) {
+		return 12, nil
+	})
+	g.AddAction("qa", sampleaction)
+	g.LinkDependency("metals", "cans")
+
+	cans, err := g.ResolveTargetValue(testContext(), newVisitordata(), "cans")
+	assert.EqualError(t, err, `action "metals": failed metals`)
+	assert.Nil(t, cans)
+
+	qa, err := g.ResolveTargetValue(testContext(), newVisitordata(), "qa")
+	assert.NoError(t, err)
+	assert.Nil(t, qa)
+
+	_, err = g.ResolveTargetValue(testContext(), newVisitordata(), "missing")
+	assert.EqualError(t, err, `action not added: "missing"`)
+	assert.Equal(t, ErrUnknownAction, errors.Cause(err))
+}
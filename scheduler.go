@@ -0,0 +1,196 @@
+package depfunc
+
+import (
+	"container/heap"
+	"sync"
+)
+
+// Scheduler bounds how many Actions execute at once across every resolve that
+// shares it, such as many concurrent resolves of the same Graph. Each resolve
+// given the Scheduler by WithScheduler has a queue of its own, and whenever an
+// Action may start, the queues take turns, so that a resolve with many ready
+// Actions cannot starve the others.
+type Scheduler struct {
+	mx    *sync.Mutex
+	limit int
+
+	// running is how many tasks are executing
+	running int
+
+	// queues are the queues with pending tasks, taking turns in order
+	queues []*schedulerQueue
+
+	// next is the index in queues of the queue whose turn is next
+	next int
+}
+
+// schedulerQueue is the queue of tasks of a single resolve
+type schedulerQueue struct {
+	tasks []func()
+
+	// active is whether the queue is in its Scheduler's queues
+	active bool
+}
+
+// NewScheduler creates a Scheduler that executes at most limit Actions at once.
+// A limit less than 1 is treated as 1.
+func NewScheduler(limit int) *Scheduler {
+	if limit < 1 {
+		limit = 1
+	}
+	return &Scheduler{
+		mx:    &sync.Mutex{},
+		limit: limit,
+	}
+}
+
+// Running returns how many Actions the Scheduler is executing
+func (s *Scheduler) Running() int {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	return s.running
+}
+
+// Pending returns how many Actions are waiting for the Scheduler to execute them
+func (s *Scheduler) Pending() int {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	pending := 0
+	for _, q := range s.queues {
+		pending += len(q.tasks)
+	}
+	return pending
+}
+
+// executor returns an executor that submits the tasks of a single resolve to a queue of its own
+func (s *Scheduler) executor() func(task func()) {
+	q := &schedulerQueue{}
+	return func(task func()) {
+		s.submit(q, task)
+	}
+}
+
+func (s *Scheduler) submit(q *schedulerQueue, task func()) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	q.tasks = append(q.tasks, task)
+	if !q.active {
+		q.active = true
+		s.queues = append(s.queues, q)
+	}
+	s.dispatch()
+}
+
+// dispatch starts tasks from the queues in turn while fewer than limit are running.
+// It must be called with the lock held.
+func (s *Scheduler) dispatch() {
+	for s.running < s.limit && len(s.queues) > 0 {
+		if s.next >= len(s.queues) {
+			s.next = 0
+		}
+		q := s.queues[s.next]
+		task := q.tasks[0]
+		q.tasks[0] = nil
+		q.tasks = q.tasks[1:]
+		if len(q.tasks) == 0 {
+			// The following queue moves into this one's place, so its turn is next
+			q.active = false
+			s.queues = append(s.queues[:s.next], s.queues[s.next+1:]...)
+		} else {
+			s.next++
+		}
+		s.running++
+		go s.run(task)
+	}
+}
+
+func (s *Scheduler) run(task func()) {
+	task()
+	s.mx.Lock()
+	s.running--
+	s.dispatch()
+	s.mx.Unlock()
+}
+
+// serialQueue executes the ready actions of a single resolve one at a time,
+// in the order they were added to the graph
+type serialQueue struct {
+	mx    *sync.Mutex
+	rank  map[string]int
+	tasks serialTasks
+
+	// started is whether tasks may execute, once every action ready as the resolve began is queued
+	started bool
+
+	// running is whether a goroutine is executing the tasks
+	running bool
+}
+
+// serialTask is a task and the rank of its action, the order it was added to the graph in
+type serialTask struct {
+	rank int
+	task func()
+}
+
+// serialTasks is a heap of tasks, lowest rank first
+type serialTasks []serialTask
+
+func newSerialQueue(rank map[string]int) *serialQueue {
+	return &serialQueue{mx: &sync.Mutex{}, tasks: serialTasks{}, rank: rank}
+}
+
+// push queues the task of the action name
+func (q *serialQueue) push(name string, task func()) {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+	heap.Push(&q.tasks, serialTask{rank: q.rank[name], task: task})
+	q.drain()
+}
+
+// start lets the queued tasks execute
+func (q *serialQueue) start() {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+	q.started = true
+	q.drain()
+}
+
+// drain starts executing the queued tasks unless they already are.
+// It must be called with the lock held.
+func (q *serialQueue) drain() {
+	if !q.started || q.running || len(q.tasks) == 0 {
+		return
+	}
+	q.running = true
+	go q.run()
+}
+
+// run executes the queued tasks, lowest rank first, until there are none
+func (q *serialQueue) run() {
+	for {
+		q.mx.Lock()
+		if len(q.tasks) == 0 {
+			q.running = false
+			q.mx.Unlock()
+			return
+		}
+		next := heap.Pop(&q.tasks).(serialTask)
+		q.mx.Unlock()
+		next.task()
+	}
+}
+
+func (t serialTasks) Len() int { return len(t) }
+
+func (t serialTasks) Less(i, j int) bool { return t[i].rank < t[j].rank }
+
+func (t serialTasks) Swap(i, j int) { t[i], t[j] = t[j], t[i] }
+
+func (t *serialTasks) Push(x interface{}) { *t = append(*t, x.(serialTask)) }
+
+func (t *serialTasks) Pop() interface{} {
+	old := *t
+	last := old[len(old)-1]
+	*t = old[:len(old)-1]
+	return last
+}
@@ -0,0 +1,79 @@
+package depfunc
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduler_roundRobin(t *testing.T) {
+	s := NewScheduler(1)
+	a, b := s.executor(), s.executor()
+	release := make(chan struct{})
+	mx := &sync.Mutex{}
+	var order []string
+	wg := &sync.WaitGroup{}
+	task := func(name string) func() {
+		wg.Add(1)
+		return func() {
+			defer wg.Done()
+			mx.Lock()
+			order = append(order, name)
+			mx.Unlock()
+		}
+	}
+
+	wg.Add(1)
+	a(func() {
+		defer wg.Done()
+		<-release
+	})
+	a(task("a1"))
+	a(task("a2"))
+	a(task("a3"))
+	b(task("b1"))
+	b(task("b2"))
+	assert.Equal(t, 1, s.Running())
+	assert.Equal(t, 5, s.Pending())
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, []string{"a1", "b1", "a2", "b2", "a3"}, order)
+}
+
+func TestGraph_ResolveWith_scheduler(t *testing.T) {
+	const limit, resolves = 3, 8
+	scheduler := NewScheduler(limit)
+	var running, peak int64
+	g := deepGraph(t, 4)
+	g.Use(func(name string, next Action) Action {
+		return func(ctx context.Context, arg interface{}) {
+			n := atomic.AddInt64(&running, 1)
+			for p := atomic.LoadInt64(&peak); n > p && !atomic.CompareAndSwapInt64(&peak, p, n); p = atomic.LoadInt64(&peak) {
+			}
+			next(ctx, arg)
+			atomic.AddInt64(&running, -1)
+		}
+	})
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < resolves; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			visitorData := newVisitordata()
+			r, err := g.Start(testContext(), visitorData, WithScheduler(scheduler), WithLazyLaunch())
+			if assert.NoError(t, err) {
+				assert.NoError(t, r.Wait())
+				assert.Len(t, visitorData.visited, 31)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.True(t, peak <= limit, "peak %d", peak)
+	assert.Equal(t, 0, scheduler.Running())
+}
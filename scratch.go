@@ -0,0 +1,78 @@
+package depfunc
+
+import "sync"
+
+// Scratch is reusable memory for the bookkeeping of a resolve.
+// Resolving with WithScratch clears and reuses the sets and wait groups of a
+// Scratch instead of allocating new ones, so that resolving Graphs of similar
+// sizes over and over allocates less. Scratches can be kept in a sync.Pool.
+//
+// A Scratch is used by one resolve at a time. It must not be reused until every
+// Action of the resolve using it has exited, as signalled by Resolution.Done.
+// The context returned by ResolveWith is only a signal of this if the resolve
+// succeeded, since it is cancelled as soon as an Action fails. The Resolution
+// of a resolve no longer reports on its Actions once its Scratch is reused.
+type Scratch struct {
+	waits     map[string]*sync.WaitGroup
+	visited   StringSet
+	path      StringSet
+	checked   StringSet
+	started   *SyncStringSet
+	completed *SyncStringSet
+
+	// groups are the wait groups handed out by waitGroup, the first used of which are in use
+	groups []sync.WaitGroup
+	used   int
+}
+
+// NewScratch creates an empty Scratch
+func NewScratch() *Scratch {
+	return &Scratch{
+		waits:     make(map[string]*sync.WaitGroup),
+		visited:   make(StringSet),
+		path:      make(StringSet),
+		checked:   make(StringSet),
+		started:   NewSyncStringSet(),
+		completed: NewSyncStringSet(),
+	}
+}
+
+// reset clears the Scratch for a resolve of size actions
+func (sc *Scratch) reset(size int) {
+	for name := range sc.waits {
+		delete(sc.waits, name)
+	}
+	clearStringSet(sc.visited)
+	clearStringSet(sc.path)
+	clearStringSet(sc.checked)
+	clearStringSet(sc.started.set)
+	clearStringSet(sc.completed.set)
+
+	if cap(sc.groups) < size {
+		sc.groups = make([]sync.WaitGroup, size)
+	} else {
+		for i := 0; i < sc.used; i++ {
+			// A wait group of an aborted resolve may never have been done
+			sc.groups[i] = sync.WaitGroup{}
+		}
+		sc.groups = sc.groups[:size]
+	}
+	sc.used = 0
+}
+
+// waitGroup returns a zeroed wait group, from groups while any are left
+func (sc *Scratch) waitGroup() *sync.WaitGroup {
+	if sc.used == len(sc.groups) {
+		return &sync.WaitGroup{}
+	}
+	wg := &sc.groups[sc.used]
+	sc.used++
+	return wg
+}
+
+// clearStringSet removes every string from ss
+func clearStringSet(ss StringSet) {
+	for s := range ss {
+		delete(ss, s)
+	}
+}
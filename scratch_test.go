@@ -0,0 +1,65 @@
+package depfunc
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraph_ResolveWith_scratch(t *testing.T) {
+	g := deepGraph(t, 6)
+	scratch := NewScratch()
+
+	for i := 0; i < 3; i++ {
+		visitorData := newVisitordata()
+		r, err := g.Start(testContext(), visitorData, WithScratch(scratch))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.NoError(t, r.Wait())
+		assert.Len(t, visitorData.visited, len(g.actions))
+	}
+}
+
+func TestGraph_ResolveWith_scratchAfterAbort(t *testing.T) {
+	g := NewGraph()
+	g.AddActionE("a", failingAction("a"))
+	g.AddAction("b", visitorAction("b"))
+	g.AddAction("c", visitorAction("c"))
+	g.LinkDependency("a", "c")
+	g.LinkDependency("b", "c")
+	scratch := NewScratch()
+
+	r, err := g.Start(testContext(), newVisitordata(), WithScratch(scratch))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Error(t, r.Wait())
+
+	g.AddAction("a", visitorAction("a"))
+	visitorData := newVisitordata()
+	r, err = g.Start(testContext(), visitorData, WithScratch(scratch))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, r.Wait())
+	assert.Len(t, visitorData.visited, 3)
+}
+
+func BenchmarkGraph_Resolve_scratch(b *testing.B) {
+	g := deepGraph(b, 10)
+	pool := &sync.Pool{New: func() interface{} { return NewScratch() }}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		scratch := pool.Get().(*Scratch)
+		visitorData := newVisitordata()
+		ctx, _ := g.ResolveWith(context.Background(), visitorData, WithScratch(scratch))
+		<-ctx.Done()
+		pool.Put(scratch)
+	}
+}
@@ -0,0 +1,73 @@
+package depfunc
+
+import "github.com/pkg/errors"
+
+// Step is an Action in a Pipeline
+type Step struct {
+	Name   string
+	Action Action
+}
+
+// Pipeline creates a Graph that executes steps one after another,
+// each depending on the step before it.
+// An error is returned if any step's name is empty or used by an earlier step,
+// or if its Action is nil.
+func Pipeline(steps ...Step) (*Graph, error) {
+	names := make([]string, len(steps))
+	seen := make(StringSet, len(steps))
+	for i, step := range steps {
+		if step.Name == "" {
+			return nil, errors.Errorf("step %d: name must not be empty", i)
+		}
+		if seen.Contains(step.Name) {
+			return nil, errors.Errorf("step %q is duplicated", step.Name)
+		}
+		seen.Add(step.Name)
+		names[i] = step.Name
+	}
+
+	g := NewGraph()
+	for _, step := range steps {
+		if err := g.AddAction(step.Name, step.Action); err != nil {
+			return nil, errors.Wrapf(err, "step %q", step.Name)
+		}
+	}
+	if err := g.LinkChain(names...); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// LinkChain links already added actions into a chain in which
+// each action depends on the action before it, so that
+// LinkChain("a", "b", "c") executes a, then b, then c.
+func (g *Graph) LinkChain(names ...string) error {
+	for i := 1; i < len(names); i++ {
+		if err := g.LinkDependency(names[i-1], names[i]); err != nil {
+			return errors.Wrapf(err, "linking %s->%s", names[i-1], names[i])
+		}
+	}
+	return nil
+}
+
+// LinkFanOut links already added actions so that each of children depends on root,
+// and executes in parallel once root has finished
+func (g *Graph) LinkFanOut(root string, children ...string) error {
+	for _, child := range children {
+		if err := g.LinkDependency(root, child); err != nil {
+			return errors.Wrapf(err, "linking %s->%s", root, child)
+		}
+	}
+	return nil
+}
+
+// LinkFanIn links already added actions so that sink depends on each of sources,
+// and executes once all of them have finished
+func (g *Graph) LinkFanIn(sink string, sources ...string) error {
+	for _, source := range sources {
+		if err := g.LinkDependency(source, sink); err != nil {
+			return errors.Wrapf(err, "linking %s->%s", source, sink)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package depfunc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPipeline(t *testing.T) {
+	g, err := Pipeline(
+		Step{Name: "a", Action: visitorAction("a")},
+		Step{Name: "b", Action: visitorAction("b")},
+		Step{Name: "c", Action: visitorAction("c")},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	visitorData := newVisitordata()
+
+	ctx, err := g.Resolve(testContext(), visitorData)
+	<-ctx.Done()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, visitorData.visited)
+	assert.Equal(t, [][2]string{{"a", "b"}, {"b", "c"}}, g.Edges())
+}
+
+func TestPipeline_errors(t *testing.T) {
+	_, err := Pipeline(Step{Name: "a", Action: sampleaction}, Step{Action: sampleaction})
+	assert.EqualError(t, err, "step 1: name must not be empty")
+
+	_, err = Pipeline(Step{Name: "a", Action: sampleaction}, Step{Name: "a", Action: sampleaction})
+	assert.EqualError(t, err, `step "a" is duplicated`)
+}
+
+func TestGraph_LinkChain(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", sampleaction)
+	g.AddAction("b", sampleaction)
+	g.AddAction("c", sampleaction)
+
+	assert.NoError(t, g.LinkChain("a", "b", "c"))
+	assert.Equal(t, [][2]string{{"a", "b"}, {"b", "c"}}, g.Edges())
+	assert.EqualError(t, g.LinkChain("c", "x"), `linking c->x: action not added: "x"`)
+}
+
+func TestGraph_LinkFanOut_LinkFanIn(t *testing.T) {
+	g := NewGraph()
+	for _, name := range []string{"produce", "w1", "w2", "aggregate"} {
+		g.AddAction(name, visitorAction(name))
+	}
+
+	assert.NoError(t, g.LinkFanOut("produce", "w1", "w2"))
+	assert.NoError(t, g.LinkFanIn("aggregate", "w1", "w2"))
+	assert.Equal(t, [][2]string{
+		{"produce", "w1"}, {"produce", "w2"},
+		{"w1", "aggregate"}, {"w2", "aggregate"},
+	}, g.Edges())
+
+	visitorData := newVisitordata()
+	ctx, err := g.Resolve(testContext(), visitorData)
+	<-ctx.Done()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "produce", visitorData.visited[0])
+	assert.Equal(t, "aggregate", visitorData.visited[3])
+}
+
+func TestGraph_LinkFanOut_LinkFanIn_errors(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", sampleaction)
+
+	assert.EqualError(t, g.LinkFanOut("a", "x"), `linking a->x: action not added: "x"`)
+	assert.EqualError(t, g.LinkFanIn("a", "x"), `linking x->a: parent action not added: "x"`)
+}
@@ -1,6 +1,7 @@
 package depfunc
 
 import (
+	"sort"
 	"sync"
 	"time"
 )
@@ -9,7 +10,9 @@ import (
 // when resolving a Graph
 type Recorder interface {
 	// Enter is when an Action is prepared
-	// to be resolved
+	// to be resolved. Every Action is entered before
+	// any Action starts, except those added while
+	// resolving by Node.AddDependent.
 	Enter(name string)
 
 	// Start is when an Action begins execution
@@ -23,59 +26,481 @@ type Recorder interface {
 	Exit(name string)
 }
 
+// ErrorRecorder is an optional extension of Recorder
+// that is notified of errors returned by ActionE actions
+type ErrorRecorder interface {
+	// Error is when an Action returned an error
+	// that cancelled the resolve
+	Error(name string, err error)
+
+	// Swallow is when a continue-on-error Action returned
+	// an error that was ignored
+	Swallow(name string, err error)
+}
+
+// recordError notifies recorder of an error if it is an ErrorRecorder
+func recordError(recorder Recorder, name string, err error) {
+	if er, ok := recorder.(ErrorRecorder); ok {
+		er.Error(name, err)
+	}
+}
+
+// recordSwallow notifies recorder of a swallowed error if it is an ErrorRecorder
+func recordSwallow(recorder Recorder, name string, err error) {
+	if er, ok := recorder.(ErrorRecorder); ok {
+		er.Swallow(name, err)
+	}
+}
+
+// SkipRecorder is an optional extension of Recorder
+// that is notified of Actions that are skipped by a resolve
+type SkipRecorder interface {
+	// Skip is when an Action will not be executed,
+	// either because it was skipped or because a dependency was
+	Skip(name string)
+}
+
+// recordSkip notifies recorder of a skipped Action if it is a SkipRecorder
+func recordSkip(recorder Recorder, name string) {
+	if sr, ok := recorder.(SkipRecorder); ok {
+		sr.Skip(name)
+	}
+}
+
+// claimer is implemented by Recorders that may only be used by a single resolve
+type claimer interface {
+	claim() error
+	release()
+}
+
+// claimRecorders claims each recorder that may only be used by a single resolve,
+// and returns the function that releases them. If any cannot be claimed, those
+// claimed already are released.
+func claimRecorders(recorders []Recorder) (func(), error) {
+	var claimed []claimer
+	release := func() {
+		for _, c := range claimed {
+			c.release()
+		}
+	}
+	for _, recorder := range recorders {
+		if c, ok := recorder.(claimer); ok {
+			if err := c.claim(); err != nil {
+				release()
+				return func() {}, err
+			}
+			claimed = append(claimed, c)
+		}
+	}
+	return release, nil
+}
+
+// AbortRecorder is an optional extension of Recorder
+// that is notified of Actions aborted because a dependency failed
+type AbortRecorder interface {
+	// Abort is when an Action will not be executed because a dependency
+	// failed, timed out or was itself aborted
+	Abort(name string)
+}
+
+// recordAbort notifies recorder of an aborted Action if it is an AbortRecorder
+func recordAbort(recorder Recorder, name string) {
+	if ar, ok := recorder.(AbortRecorder); ok {
+		ar.Abort(name)
+	}
+}
+
+// GateRecorder is an optional extension of Recorder
+// that is notified of Actions that a Gate did not allow to execute
+type GateRecorder interface {
+	// Gate is when an Action will not be executed because a Gate did not allow it.
+	// The Action is also reported as skipped to SkipRecorders.
+	Gate(name string)
+}
+
+// recordGate notifies recorder of a gated Action if it is a GateRecorder
+func recordGate(recorder Recorder, name string) {
+	if gr, ok := recorder.(GateRecorder); ok {
+		gr.Gate(name)
+	}
+}
+
+// RetryRecorder is an optional extension of Recorder
+// that is notified of Actions retried by WithRetry
+type RetryRecorder interface {
+	// Retry is when an Action failed with err and is executed again, for the attempt-th time
+	Retry(name string, attempt int, err error)
+
+	// GiveUp is when an Action failed and is not retried again because of reason,
+	// which is ErrAttemptsExhausted or ErrBudgetExhausted
+	GiveUp(name string, reason error)
+}
+
+// recordRetry notifies recorder of a retried Action if it is a RetryRecorder
+func recordRetry(recorder Recorder, name string, attempt int, err error) {
+	if rr, ok := recorder.(RetryRecorder); ok {
+		rr.Retry(name, attempt, err)
+	}
+}
+
+// recordGiveUp notifies recorder of an Action that is no longer retried if it is a RetryRecorder
+func recordGiveUp(recorder Recorder, name string, reason error) {
+	if rr, ok := recorder.(RetryRecorder); ok {
+		rr.GiveUp(name, reason)
+	}
+}
+
+// NoChangeRecorder is an optional extension of Recorder
+// that is notified of Actions that returned ErrNoChange
+type NoChangeRecorder interface {
+	// NoChange is when an Action finished without having anything to do.
+	// It is reported before the Action finishes.
+	NoChange(name string)
+}
+
+// recordNoChange notifies recorder of an Action that made no change if it is a NoChangeRecorder
+func recordNoChange(recorder Recorder, name string) {
+	if nr, ok := recorder.(NoChangeRecorder); ok {
+		nr.NoChange(name)
+	}
+}
+
+// TimeoutRecorder is an optional extension of Recorder
+// that is notified of Actions that exceeded their WithActionTimeout
+type TimeoutRecorder interface {
+	// Timeout is when an Action's own deadline passed before it returned.
+	// It is not reported when the whole resolve was cancelled or timed out.
+	Timeout(name string)
+}
+
+// recordTimeout notifies recorder of a timed out Action if it is a TimeoutRecorder
+func recordTimeout(recorder Recorder, name string) {
+	if tr, ok := recorder.(TimeoutRecorder); ok {
+		tr.Timeout(name)
+	}
+}
+
+// PanicRecorder is an optional extension of Recorder
+// that is notified of Actions that panicked
+type PanicRecorder interface {
+	// Panic is when an Action panicked with v. The panic was recovered
+	// and the Action failed with an error caused by ErrPanic, which is
+	// also reported to ErrorRecorders.
+	Panic(name string, v interface{})
+}
+
+// recordPanic notifies recorder of a panicked Action if it is a PanicRecorder
+func recordPanic(recorder Recorder, name string, v interface{}) {
+	if pr, ok := recorder.(PanicRecorder); ok {
+		pr.Panic(name, v)
+	}
+}
+
+// BaseRecorder is a Recorder that does nothing, including for every optional
+// extension of Recorder. Embedding it in a Recorder implements every event that
+// the Recorder does not, so the Recorder keeps compiling as extensions are added.
+type BaseRecorder struct{}
+
+func (BaseRecorder) Enter(name string) {}
+
+func (BaseRecorder) Start(name string) {}
+
+func (BaseRecorder) Finish(name string) {}
+
+func (BaseRecorder) Exit(name string) {}
+
+func (BaseRecorder) Error(name string, err error) {}
+
+func (BaseRecorder) Swallow(name string, err error) {}
+
+func (BaseRecorder) Skip(name string) {}
+
+func (BaseRecorder) Abort(name string) {}
+
+func (BaseRecorder) Gate(name string) {}
+
+func (BaseRecorder) Retry(name string, attempt int, err error) {}
+
+func (BaseRecorder) GiveUp(name string, reason error) {}
+
+func (BaseRecorder) NoChange(name string) {}
+
+func (BaseRecorder) Timeout(name string) {}
+
+func (BaseRecorder) Panic(name string, v interface{}) {}
+
+// IdentifiedRecorder is an optional extension of Recorder
+// that can tell apart the resolves identified by WithID
+type IdentifiedRecorder interface {
+	Recorder
+
+	// WithID returns the Recorder that records the resolve identified by id
+	WithID(id string) Recorder
+}
+
 // Statistics is a way to get statistics about resolved (or cancelled) actions.
 // To record statistics, use the .Recorder() method to get a Recorder
-// that can be used with Resolve. Statistics should not be re-used between Resolves.
+// that can be used with Resolve. Statistics cannot be re-used between Resolves
+// until they are Reset: a resolve given a Recorder from a Statistics that is
+// already in use returns ErrStatisticsInUse. A resolve that fails to set up,
+// such as with ErrCycle, does not keep the Statistics in use once the Actions
+// it visited, if any, have exited.
 type Statistics struct {
 	*sync.RWMutex
-	enter  map[string]time.Time
-	start  map[string]time.Time
-	finish map[string]time.Time
-	exit   map[string]time.Time
+	enter    map[string]time.Time
+	start    map[string]time.Time
+	finish   map[string]time.Time
+	exit     map[string]time.Time
+	errs     map[string]error
+	panics   StringSet
+	timeouts StringSet
+	aborts   StringSet
+
+	// now returns the time of an event
+	now func() time.Time
 
 	recorder *timeRecorder
 }
 
 // NewStatistics creates a new Statistics. Statistics can be used to analyze a Resolve.
-// It should not be re-used between multiple Graph Resolves.
+// It cannot be re-used between multiple Graph Resolves.
 func NewStatistics() *Statistics {
+	return NewStatisticsWithClock(time.Now)
+}
+
+// NewStatisticsWithClock creates a new Statistics that times events with now
+// instead of time.Now, such as a fake clock that makes durations predictable in tests.
+// now is called while the Statistics is locked, so it must not use the Statistics.
+func NewStatisticsWithClock(now func() time.Time) *Statistics {
 	p := &Statistics{
-		RWMutex: &sync.RWMutex{},
-		enter:   make(map[string]time.Time),
-		start:   make(map[string]time.Time),
-		finish:  make(map[string]time.Time),
-		exit:    make(map[string]time.Time),
+		RWMutex:  &sync.RWMutex{},
+		enter:    make(map[string]time.Time),
+		start:    make(map[string]time.Time),
+		finish:   make(map[string]time.Time),
+		exit:     make(map[string]time.Time),
+		errs:     make(map[string]error),
+		panics:   make(StringSet),
+		timeouts: make(StringSet),
+		aborts:   make(StringSet),
+		now:      now,
 	}
 
 	return p
 }
 
 // Recorder returns a Recorder that will record details into this Statistics.
-// It should not be re-used between multiple Graph Resolves.
+// It cannot be re-used between multiple Graph Resolves.
 func (s *Statistics) Recorder() Recorder {
 	if s.recorder == nil {
 		s.recorder = &timeRecorder{
-			RWMutex: s.RWMutex,
-			enter:   s.enter,
-			start:   s.start,
-			finish:  s.finish,
-			exit:    s.exit,
+			RWMutex:  s.RWMutex,
+			enter:    s.enter,
+			start:    s.start,
+			finish:   s.finish,
+			exit:     s.exit,
+			errs:     s.errs,
+			panics:   s.panics,
+			timeouts: s.timeouts,
+			aborts:   s.aborts,
+			now:      s.now,
 		}
 	}
 	return s.recorder
 }
 
+// InUse returns if this Statistics' Recorder has been used by a resolve
+func (s *Statistics) InUse() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.recorder != nil && s.recorder.used
+}
+
+// Reset forgets everything recorded so that the Statistics, and its Recorder,
+// can be used by another resolve. The memory used by the previous resolve is
+// kept for the next, so a service that resolves repeatedly does not allocate
+// a new Statistics each time. Reset must not be called while a resolve that
+// uses the Recorder has not yet finished; read what is needed from it, or
+// take a Snapshot, before resetting.
+func (s *Statistics) Reset() {
+	s.Lock()
+	defer s.Unlock()
+	clearTimes(s.enter)
+	clearTimes(s.start)
+	clearTimes(s.finish)
+	clearTimes(s.exit)
+	for name := range s.errs {
+		delete(s.errs, name)
+	}
+	clearStringSet(s.panics)
+	clearStringSet(s.timeouts)
+	clearStringSet(s.aborts)
+	if s.recorder != nil {
+		s.recorder.used = false
+	}
+}
+
+func clearTimes(m map[string]time.Time) {
+	for key := range m {
+		delete(m, key)
+	}
+}
+
+// Snapshot returns a copy of everything recorded so far. Each accessor of
+// Statistics reads under a lock, but successive calls during a resolve may
+// observe different states; a StatsSnapshot is consistent and never changes.
+func (s *Statistics) Snapshot() StatsSnapshot {
+	s.RLock()
+	defer s.RUnlock()
+	return StatsSnapshot{
+		enter:    copyTimes(s.enter),
+		start:    copyTimes(s.start),
+		finish:   copyTimes(s.finish),
+		exit:     copyTimes(s.exit),
+		errs:     copyErrors(s.errs),
+		panics:   s.panics.Union(nil),
+		timeouts: s.timeouts.Union(nil),
+		aborts:   s.aborts.Union(nil),
+	}
+}
+
+// view returns a StatsSnapshot that shares this Statistics' maps,
+// which may only be used while holding the lock
+func (s *Statistics) view() StatsSnapshot {
+	return StatsSnapshot{
+		enter:    s.enter,
+		start:    s.start,
+		finish:   s.finish,
+		exit:     s.exit,
+		errs:     s.errs,
+		panics:   s.panics,
+		timeouts: s.timeouts,
+		aborts:   s.aborts,
+	}
+}
+
 // Names returns the set of Names this Statistics has information about.
 func (s *Statistics) Names() StringSet {
-	ss := make(StringSet)
+	s.RLock()
+	defer s.RUnlock()
+	return s.view().Names()
+}
+
+// Action returns the duration of the actual execution of
+// an Action, or 0 if the action was not executed.
+func (s *Statistics) Action(name string) time.Duration {
+	s.RLock()
+	defer s.RUnlock()
+	return s.view().Action(name)
+}
+
+// Wait returns how long the Action waited to be executed
+// or 0 if the action was not executed.
+func (s *Statistics) Wait(name string) time.Duration {
+	s.RLock()
+	defer s.RUnlock()
+	return s.view().Wait(name)
+}
+
+// Executed returns if the Action began execution, distinguishing an Action
+// that ran for no measurable time from one that never ran at all.
+func (s *Statistics) Executed(name string) bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.view().Executed(name)
+}
+
+// Err returns the error the Action returned, whether or not it was swallowed,
+// or nil if it did not return one.
+func (s *Statistics) Err(name string) error {
+	s.RLock()
+	defer s.RUnlock()
+	return s.view().Err(name)
+}
+
+// Panicked returns the set of Actions that panicked
+func (s *Statistics) Panicked() StringSet {
+	s.RLock()
+	defer s.RUnlock()
+	return s.view().Panicked()
+}
+
+// TimedOut returns the sorted names of the Actions whose own deadline, set by
+// WithActionTimeout, passed before they returned. Actions aborted because the
+// whole resolve was cancelled or timed out are not included.
+func (s *Statistics) TimedOut() []string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.view().TimedOut()
+}
+
+// Total returns the amount of time between preparing the Action
+// and either aborting or finishing execution of the Action.
+func (s *Statistics) Total(name string) time.Duration {
+	s.RLock()
+	defer s.RUnlock()
+	return s.view().Total(name)
+}
+
+// BlockingDependency returns the dependency of the Action in g that finished last,
+// and so was the one that unblocked it, or an empty string if the Action has no
+// dependencies that finished. Ties are broken by name.
+func (s *Statistics) BlockingDependency(g *Graph, name string) string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.view().BlockingDependency(g, name)
+}
 
+// Timeline returns the execution interval of every Action that started,
+// sorted by start time. Actions that never started are omitted.
+func (s *Statistics) Timeline() []TimelineEntry {
 	s.RLock()
+	defer s.RUnlock()
+	return s.view().Timeline()
+}
+
+// Summary returns aggregate statistics about every Action, computed consistently
+func (s *Statistics) Summary() StatsSummary {
+	s.RLock()
+	defer s.RUnlock()
+	return s.view().Summary()
+}
+
+// StatsSnapshot is an immutable copy of a Statistics,
+// safe to read without locking while a resolve continues
+type StatsSnapshot struct {
+	enter    map[string]time.Time
+	start    map[string]time.Time
+	finish   map[string]time.Time
+	exit     map[string]time.Time
+	errs     map[string]error
+	panics   StringSet
+	timeouts StringSet
+	aborts   StringSet
+}
+
+func copyTimes(m map[string]time.Time) map[string]time.Time {
+	c := make(map[string]time.Time, len(m))
+	for key, t := range m {
+		c[key] = t
+	}
+	return c
+}
+
+func copyErrors(m map[string]error) map[string]error {
+	c := make(map[string]error, len(m))
+	for key, err := range m {
+		c[key] = err
+	}
+	return c
+}
+
+// Names returns the set of Names this StatsSnapshot has information about.
+func (s StatsSnapshot) Names() StringSet {
+	ss := make(StringSet)
 	addKeys(ss, s.enter)
 	addKeys(ss, s.start)
 	addKeys(ss, s.finish)
 	addKeys(ss, s.exit)
-	s.RUnlock()
-
 	return ss
 }
 
@@ -85,10 +510,7 @@ func addKeys(ss StringSet, m map[string]time.Time) {
 	}
 }
 
-func (s *Statistics) duration(from, to map[string]time.Time, name string) time.Duration {
-	s.RLock()
-	defer s.RUnlock()
-
+func (s StatsSnapshot) duration(from, to map[string]time.Time, name string) time.Duration {
 	b, ok := to[name]
 	if !ok {
 		return 0
@@ -102,34 +524,210 @@ func (s *Statistics) duration(from, to map[string]time.Time, name string) time.D
 
 // Action returns the duration of the actual execution of
 // an Action, or 0 if the action was not executed.
-func (s *Statistics) Action(name string) time.Duration {
+func (s StatsSnapshot) Action(name string) time.Duration {
 	return s.duration(s.start, s.finish, name)
 }
 
 // Wait returns how long the Action waited to be executed
 // or 0 if the action was not executed.
-func (s *Statistics) Wait(name string) time.Duration {
+func (s StatsSnapshot) Wait(name string) time.Duration {
 	return s.duration(s.enter, s.start, name)
 }
 
+// Executed returns if the Action began execution.
+func (s StatsSnapshot) Executed(name string) bool {
+	_, ok := s.start[name]
+	return ok
+}
+
+// Err returns the error the Action returned, whether or not it was swallowed,
+// or nil if it did not return one.
+func (s StatsSnapshot) Err(name string) error {
+	return s.errs[name]
+}
+
+// Succeeded returns the set of Actions that finished without returning an error.
+func (s StatsSnapshot) Succeeded() StringSet {
+	ss := make(StringSet)
+	for name := range s.finish {
+		if s.errs[name] == nil {
+			ss.Add(name)
+		}
+	}
+	return ss
+}
+
+// Panicked returns the set of Actions that panicked
+func (s StatsSnapshot) Panicked() StringSet {
+	return s.panics.Union(nil)
+}
+
+// TimedOut returns the sorted names of the Actions whose own deadline passed before they returned.
+// See Statistics.TimedOut.
+func (s StatsSnapshot) TimedOut() []string {
+	return sortedNames(s.timeouts)
+}
+
 // Total returns the amount of time between preparing the Action
 // and either aborting or finishing execution of the Action.
-func (s *Statistics) Total(name string) time.Duration {
+func (s StatsSnapshot) Total(name string) time.Duration {
 	return s.duration(s.enter, s.exit, name)
 }
 
+// BlockingDependency returns the dependency of the Action in g that finished last.
+// See Statistics.BlockingDependency.
+func (s StatsSnapshot) BlockingDependency(g *Graph, name string) string {
+	var blocking string
+	var latest time.Time
+	for _, dependency := range sortedNames(g.treeOrder[name]) {
+		finish, ok := s.finish[dependency]
+		if ok && (blocking == "" || finish.After(latest)) {
+			blocking, latest = dependency, finish
+		}
+	}
+	return blocking
+}
+
+// StatsSummary is an aggregate of the statistics of every Action in a resolve
+type StatsSummary struct {
+	// Span is the time from when the first Action was entered until the last exited
+	Span time.Duration
+
+	// ActionTime is the sum of the execution time of every Action
+	ActionTime time.Duration
+
+	// MaxWait is the longest any Action waited to be executed
+	MaxWait time.Duration
+
+	// Slowest is the name of the Action that took the longest to execute
+	Slowest string
+
+	// SlowestAction is how long the slowest Action took to execute
+	SlowestAction time.Duration
+
+	// Executed is the number of Actions that began execution
+	Executed int
+
+	// Aborted is the number of Actions that exited without finishing execution
+	Aborted int
+
+	// Panicked is the number of Actions that panicked
+	Panicked int
+}
+
+// Summary returns aggregate statistics about every Action.
+// Ties for the slowest Action are broken by name.
+func (s StatsSnapshot) Summary() StatsSummary {
+	var summary StatsSummary
+	var first, last time.Time
+	for _, name := range sortedNames(s.Names()) {
+		if enter, ok := s.enter[name]; ok && (first.IsZero() || enter.Before(first)) {
+			first = enter
+		}
+		if exit, ok := s.exit[name]; ok {
+			if exit.After(last) {
+				last = exit
+			}
+			if _, finished := s.finish[name]; !finished {
+				summary.Aborted++
+			}
+		}
+		if !s.Executed(name) {
+			continue
+		}
+		summary.Executed++
+		action := s.Action(name)
+		summary.ActionTime += action
+		if summary.Slowest == "" || action > summary.SlowestAction {
+			summary.Slowest, summary.SlowestAction = name, action
+		}
+		if wait := s.Wait(name); wait > summary.MaxWait {
+			summary.MaxWait = wait
+		}
+	}
+	summary.Panicked = len(s.panics)
+	if !first.IsZero() && last.After(first) {
+		summary.Span = last.Sub(first)
+	}
+	return summary
+}
+
+// TimelineEntry is the wall-clock interval of an Action's execution
+type TimelineEntry struct {
+	// Name is the name of the Action
+	Name string
+
+	// WaitStart is when the Action was prepared to be resolved
+	WaitStart time.Time
+
+	// Start is when the Action began execution
+	Start time.Time
+
+	// Finish is when the Action finished execution,
+	// or the zero time if it has not finished
+	Finish time.Time
+}
+
+// Timeline returns the execution interval of every Action that started,
+// sorted by start time. Actions that never started are omitted.
+func (s StatsSnapshot) Timeline() []TimelineEntry {
+	entries := make([]TimelineEntry, 0, len(s.start))
+	for name, start := range s.start {
+		entries = append(entries, TimelineEntry{
+			Name:      name,
+			WaitStart: s.enter[name],
+			Start:     start,
+			Finish:    s.finish[name],
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Start.Equal(entries[j].Start) {
+			return entries[i].Name < entries[j].Name
+		}
+		return entries[i].Start.Before(entries[j].Start)
+	})
+	return entries
+}
+
 // timeRecorder is a helper for Statistics that implements the Recorder interface
 type timeRecorder struct {
 	*sync.RWMutex
-	enter  map[string]time.Time
-	start  map[string]time.Time
-	finish map[string]time.Time
-	exit   map[string]time.Time
+	enter    map[string]time.Time
+	start    map[string]time.Time
+	finish   map[string]time.Time
+	exit     map[string]time.Time
+	errs     map[string]error
+	panics   StringSet
+	timeouts StringSet
+	aborts   StringSet
+
+	// now returns the time of an event
+	now func() time.Time
+
+	// used is whether a resolve has claimed this recorder
+	used bool
+}
+
+func (p *timeRecorder) claim() error {
+	p.Lock()
+	defer p.Unlock()
+	if p.used {
+		return ErrStatisticsInUse
+	}
+	p.used = true
+	return nil
+}
+
+func (p *timeRecorder) release() {
+	p.Lock()
+	p.used = false
+	p.Unlock()
 }
 
 func (p *timeRecorder) recordTime(m map[string]time.Time, name string) {
 	p.Lock()
-	m[name] = time.Now()
+	m[name] = p.now()
 	p.Unlock()
 }
 
@@ -149,6 +747,34 @@ func (p *timeRecorder) Exit(name string) {
 	p.recordTime(p.exit, name)
 }
 
+func (p *timeRecorder) Error(name string, err error) {
+	p.Lock()
+	p.errs[name] = err
+	p.Unlock()
+}
+
+func (p *timeRecorder) Swallow(name string, err error) {
+	p.Error(name, err)
+}
+
+func (p *timeRecorder) Timeout(name string) {
+	p.Lock()
+	p.timeouts.Add(name)
+	p.Unlock()
+}
+
+func (p *timeRecorder) Panic(name string, v interface{}) {
+	p.Lock()
+	p.panics.Add(name)
+	p.Unlock()
+}
+
+func (p *timeRecorder) Abort(name string) {
+	p.Lock()
+	p.aborts.Add(name)
+	p.Unlock()
+}
+
 // visitRecorderList is a Recorder that
 // operates on a slice of VisitRecorders
 type visitRecorderList struct {
@@ -179,6 +805,66 @@ func (v visitRecorderList) Exit(name string) {
 	}
 }
 
+func (v visitRecorderList) Error(name string, err error) {
+	for _, vr := range v.recorders {
+		recordError(vr, name, err)
+	}
+}
+
+func (v visitRecorderList) Swallow(name string, err error) {
+	for _, vr := range v.recorders {
+		recordSwallow(vr, name, err)
+	}
+}
+
+func (v visitRecorderList) Skip(name string) {
+	for _, vr := range v.recorders {
+		recordSkip(vr, name)
+	}
+}
+
+func (v visitRecorderList) Gate(name string) {
+	for _, vr := range v.recorders {
+		recordGate(vr, name)
+	}
+}
+
+func (v visitRecorderList) Retry(name string, attempt int, err error) {
+	for _, vr := range v.recorders {
+		recordRetry(vr, name, attempt, err)
+	}
+}
+
+func (v visitRecorderList) GiveUp(name string, reason error) {
+	for _, vr := range v.recorders {
+		recordGiveUp(vr, name, reason)
+	}
+}
+
+func (v visitRecorderList) NoChange(name string) {
+	for _, vr := range v.recorders {
+		recordNoChange(vr, name)
+	}
+}
+
+func (v visitRecorderList) Timeout(name string) {
+	for _, vr := range v.recorders {
+		recordTimeout(vr, name)
+	}
+}
+
+func (v visitRecorderList) Panic(name string, value interface{}) {
+	for _, vr := range v.recorders {
+		recordPanic(vr, name, value)
+	}
+}
+
+func (v visitRecorderList) Abort(name string) {
+	for _, vr := range v.recorders {
+		recordAbort(vr, name)
+	}
+}
+
 // noopVisitRecorder is a Recorder that does nothing
 type noopVisitRecorder struct{}
 
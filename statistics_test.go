@@ -0,0 +1,334 @@
+package depfunc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var epoch = time.Date(2018, 2, 15, 0, 0, 0, 0, time.UTC)
+
+func at(ms int) time.Time {
+	return epoch.Add(time.Duration(ms) * time.Millisecond)
+}
+
+// fakeClock returns a clock that starts at the epoch and advances by step each time it is read
+func fakeClock(step time.Duration) func() time.Time {
+	now := epoch
+	return func() time.Time {
+		now = now.Add(step)
+		return now
+	}
+}
+
+func TestNewStatisticsWithClock(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", visitorAction("a"))
+	stats := NewStatisticsWithClock(fakeClock(10 * time.Millisecond))
+
+	r, err := g.Start(testContext(), newVisitordata(), WithRecorders(stats.Recorder()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Wait()
+
+	assert.Equal(t, 10*time.Millisecond, stats.Wait("a"))
+	assert.Equal(t, 10*time.Millisecond, stats.Action("a"))
+	assert.Equal(t, 30*time.Millisecond, stats.Total("a"))
+	assert.Equal(t, []TimelineEntry{{Name: "a", WaitStart: at(10), Start: at(20), Finish: at(30)}}, stats.Timeline())
+}
+
+func TestStatistics_Timeline(t *testing.T) {
+	stats := NewStatistics()
+	stats.enter["a"], stats.start["a"], stats.finish["a"], stats.exit["a"] = at(0), at(10), at(20), at(20)
+	stats.enter["b"], stats.start["b"], stats.finish["b"], stats.exit["b"] = at(0), at(5), at(30), at(30)
+	stats.enter["c"], stats.exit["c"] = at(0), at(1)
+
+	timeline := stats.Timeline()
+
+	assert.Equal(t, []TimelineEntry{
+		{Name: "b", WaitStart: at(0), Start: at(5), Finish: at(30)},
+		{Name: "a", WaitStart: at(0), Start: at(10), Finish: at(20)},
+	}, timeline)
+}
+
+func TestStatistics_Timeline_resolved(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", visitorAction("a"))
+	g.AddAction("b", visitorAction("b"))
+	g.LinkDependency("a", "b")
+	stats := NewStatistics()
+
+	ctx, err := g.Resolve(testContext(), newVisitordata(), stats.Recorder())
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-ctx.Done()
+
+	timeline := stats.Timeline()
+
+	if assert.Len(t, timeline, 2) {
+		assert.Equal(t, "a", timeline[0].Name)
+		assert.Equal(t, "b", timeline[1].Name)
+		assert.False(t, timeline[1].Start.Before(timeline[0].Finish))
+	}
+}
+
+func TestStatistics_Panicked(t *testing.T) {
+	stats := NewStatistics()
+	stats.Recorder().(PanicRecorder).Panic("a", "boom")
+
+	snapshot := stats.Snapshot()
+	stats.Recorder().(PanicRecorder).Panic("b", "boom")
+
+	assert.Equal(t, stringSet("a", "b"), stats.Panicked())
+	assert.Equal(t, stringSet("a"), snapshot.Panicked())
+	assert.Equal(t, 1, snapshot.Summary().Panicked)
+}
+
+// panicCounter only implements the events it is interested in
+type panicCounter struct {
+	BaseRecorder
+	panics int
+}
+
+func (p *panicCounter) Panic(name string, v interface{}) {
+	p.panics++
+}
+
+func TestBaseRecorder(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", func(ctx context.Context, arg interface{}) {
+		panic("boom")
+	})
+	recorder := &panicCounter{}
+
+	r, err := g.Start(testContext(), nil, WithRecorders(recorder))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Wait()
+
+	assert.Equal(t, 1, recorder.panics)
+}
+
+func TestStatistics_Executed(t *testing.T) {
+	g := NewGraph()
+	g.AddActionE("a", failingAction("a"))
+	g.AddAction("b", visitorAction("b"))
+	g.LinkDependency("a", "b")
+	stats := NewStatistics()
+
+	ctx, err := g.Resolve(testContext(), newVisitordata(), stats.Recorder())
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-ctx.Done()
+
+	assert.True(t, stats.Executed("a"))
+	assert.False(t, stats.Executed("b"))
+	assert.Equal(t, time.Duration(0), stats.Action("b"))
+	assert.False(t, stats.Executed("missing"))
+}
+
+func TestStatistics_BlockingDependency(t *testing.T) {
+	g := NewGraph()
+	for _, name := range []string{"a", "b", "c", "d"} {
+		g.AddAction(name, visitorAction(name))
+	}
+	g.LinkDependency("a", "d")
+	g.LinkDependency("b", "d")
+	g.LinkDependency("c", "d")
+	stats := NewStatistics()
+	stats.finish["a"] = at(10)
+	stats.finish["b"] = at(30)
+	stats.finish["c"] = at(20)
+	stats.start["d"] = at(31)
+
+	assert.Equal(t, "b", stats.BlockingDependency(g, "d"))
+	assert.Equal(t, "", stats.BlockingDependency(g, "a"))
+}
+
+func TestStatistics_BlockingDependency_tie(t *testing.T) {
+	g := NewGraph()
+	for _, name := range []string{"a", "b", "c"} {
+		g.AddAction(name, visitorAction(name))
+	}
+	g.LinkDependency("b", "c")
+	g.LinkDependency("a", "c")
+	stats := NewStatistics()
+	stats.finish["a"] = at(10)
+	stats.finish["b"] = at(10)
+
+	assert.Equal(t, "a", stats.BlockingDependency(g, "c"))
+}
+
+func TestStatistics_InUse(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", visitorAction("a"))
+	stats := NewStatistics()
+
+	assert.False(t, stats.InUse())
+
+	ctx, err := g.Resolve(testContext(), newVisitordata(), stats.Recorder())
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-ctx.Done()
+
+	assert.True(t, stats.InUse())
+
+	visitorData := newVisitordata()
+	ctx, err = g.Resolve(testContext(), visitorData, stats.Recorder())
+	<-ctx.Done()
+
+	assert.Equal(t, ErrStatisticsInUse, err)
+	assert.Empty(t, visitorData.visited)
+}
+
+func TestStatistics_InUse_setupError(t *testing.T) {
+	cyclic := NewGraph()
+	cyclic.AddAction("a", sampleaction)
+	cyclic.AddAction("b", sampleaction)
+	cyclic.AddAction("c", sampleaction)
+	cyclic.LinkDependency("a", "b")
+	cyclic.LinkDependency("b", "a")
+	cyclic.LinkDependency("a", "c")
+	g := NewGraph()
+	g.AddAction("a", visitorAction("a"))
+	stats := NewStatistics()
+	used := NewStatistics()
+	used.Recorder().(claimer).claim()
+
+	_, err := NewGraph().Resolve(testContext(), nil, stats.Recorder())
+	assert.Equal(t, ErrNoRoots, err)
+	assert.False(t, stats.InUse())
+
+	_, err = cyclic.Resolve(testContext(), nil, stats.Recorder())
+	assert.Equal(t, ErrCycle, err)
+	assert.False(t, stats.InUse())
+
+	_, err = g.Resolve(testContext(), nil, stats.Recorder(), used.Recorder())
+	assert.Equal(t, ErrStatisticsInUse, err)
+	assert.False(t, stats.InUse())
+	assert.True(t, used.InUse())
+
+	visitorData := newVisitordata()
+	ctx, err := g.Resolve(testContext(), visitorData, stats.Recorder())
+	<-ctx.Done()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a"}, visitorData.visited)
+	assert.True(t, stats.InUse())
+}
+
+func TestStatistics_Reset(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", visitorAction("a"))
+	g.AddActionE("b", failingAction("b"))
+	stats := NewStatistics()
+
+	r, err := g.Start(testContext(), newVisitordata(), WithRecorders(stats.Recorder()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Wait()
+	stats.Reset()
+
+	assert.False(t, stats.InUse())
+	assert.Empty(t, stats.Names())
+	assert.NoError(t, stats.Err("b"))
+
+	g = NewGraph()
+	g.AddAction("c", visitorAction("c"))
+	r, err = g.Start(testContext(), newVisitordata(), WithRecorders(stats.Recorder()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Wait()
+
+	assert.True(t, stats.InUse())
+	assert.Equal(t, stringSet("c"), stats.Names())
+}
+
+func TestStatistics_Snapshot(t *testing.T) {
+	stats := NewStatistics()
+	recorder := stats.Recorder()
+	recorder.Enter("a")
+	recorder.Start("a")
+
+	snapshot := stats.Snapshot()
+	recorder.Finish("a")
+	recorder.Exit("a")
+	recorder.Enter("b")
+
+	assert.Equal(t, stringSet("a"), snapshot.Names())
+	assert.True(t, snapshot.Executed("a"))
+	assert.Equal(t, time.Duration(0), snapshot.Action("a"))
+	assert.Equal(t, stringSet("a", "b"), stats.Names())
+}
+
+func TestStatistics_Snapshot_concurrent(t *testing.T) {
+	g := deepGraph(t, 6)
+	stats := NewStatistics()
+
+	ctx, err := g.Resolve(testContext(), newVisitordata(), stats.Recorder())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		snapshot := stats.Snapshot()
+		for name := range snapshot.Names() {
+			if snapshot.Action(name) < 0 {
+				t.Fatalf("%s finished before it started", name)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+	}
+}
+
+func TestStatistics_Err(t *testing.T) {
+	g := NewGraph()
+	g.AddActionContinueOnError("a", failingAction("a"))
+	g.AddAction("b", visitorAction("b"))
+	g.LinkDependency("a", "b")
+	stats := NewStatistics()
+
+	ctx, err := g.Resolve(testContext(), newVisitordata(), stats.Recorder())
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-ctx.Done()
+
+	assert.EqualError(t, stats.Err("a"), "failed a")
+	assert.NoError(t, stats.Err("b"))
+	assert.Equal(t, stringSet("b"), stats.Snapshot().Succeeded())
+}
+
+func TestStatistics_Summary(t *testing.T) {
+	stats := NewStatistics()
+	stats.enter["a"], stats.start["a"], stats.finish["a"], stats.exit["a"] = at(0), at(10), at(20), at(20)
+	stats.enter["b"], stats.start["b"], stats.finish["b"], stats.exit["b"] = at(5), at(25), at(55), at(55)
+	stats.enter["c"], stats.exit["c"] = at(5), at(60)
+
+	summary := stats.Summary()
+
+	assert.Equal(t, StatsSummary{
+		Span:          60 * time.Millisecond,
+		ActionTime:    40 * time.Millisecond,
+		MaxWait:       20 * time.Millisecond,
+		Slowest:       "b",
+		SlowestAction: 30 * time.Millisecond,
+		Executed:      2,
+		Aborted:       1,
+	}, summary)
+}
+
+func TestStatistics_Summary_empty(t *testing.T) {
+	assert.Equal(t, StatsSummary{}, NewStatistics().Summary())
+}
@@ -0,0 +1,251 @@
+package depfunc
+
+import (
+	"context"
+	"sync"
+)
+
+// EventKind is the kind of an Event
+type EventKind int
+
+const (
+	// EventEnter is when an Action is prepared to be resolved
+	EventEnter EventKind = iota
+
+	// EventStart is when an Action begins execution
+	EventStart
+
+	// EventFinish is when an Action has finished execution
+	EventFinish
+
+	// EventExit is when an Action has finished or was aborted
+	EventExit
+
+	// EventError is when an Action returned an error that cancelled the resolve
+	EventError
+
+	// EventSwallow is when a continue-on-error Action returned an error that was ignored
+	EventSwallow
+
+	// EventSkip is when an Action will not be executed because it or a dependency was skipped
+	EventSkip
+
+	// EventAbort is when an Action will not be executed because a dependency failed
+	EventAbort
+
+	// EventGate is when an Action will not be executed because a Gate did not allow it
+	EventGate
+
+	// EventRetry is when an Action failed and is executed again
+	EventRetry
+
+	// EventGiveUp is when an Action failed and is not retried again
+	EventGiveUp
+
+	// EventPanic is when an Action panicked
+	EventPanic
+
+	// EventTimeout is when an Action's own deadline passed before it returned
+	EventTimeout
+
+	// EventNoChange is when an Action finished without having anything to do
+	EventNoChange
+)
+
+var eventKindNames = map[EventKind]string{
+	EventEnter:    "enter",
+	EventStart:    "start",
+	EventFinish:   "finish",
+	EventExit:     "exit",
+	EventError:    "error",
+	EventSwallow:  "swallow",
+	EventSkip:     "skip",
+	EventAbort:    "abort",
+	EventGate:     "gate",
+	EventRetry:    "retry",
+	EventGiveUp:   "giveup",
+	EventPanic:    "panic",
+	EventTimeout:  "timeout",
+	EventNoChange: "nochange",
+}
+
+func (k EventKind) String() string {
+	if name, ok := eventKindNames[k]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// Event is a Recorder event emitted by ResolveStream
+type Event struct {
+	// Name is the name of the Action
+	Name string
+
+	// Kind is what happened to the Action
+	Kind EventKind
+
+	// Err is the error returned by the Action for EventError, EventSwallow and EventRetry,
+	// or the reason it is not retried again for EventGiveUp
+	Err error
+
+	// Attempt is the attempt that is starting for EventRetry
+	Attempt int
+
+	// Value is what the Action panicked with for EventPanic
+	Value interface{}
+
+	// ID is the ID of the resolve, if it was identified by WithID
+	ID string
+}
+
+// ResolveStream executes this Graph on a given context and emits an Event
+// for everything that happens to each Action as it happens.
+// It is shorthand for ResolveStreamWith and WithRecorders.
+func (g *Graph) ResolveStream(ctx context.Context, arg interface{}, recorders ...Recorder) (<-chan Event, error) {
+	return g.ResolveStreamWith(ctx, arg, WithRecorders(recorders...))
+}
+
+// ResolveStreamWith executes this Graph on a given context, configured by opts,
+// and emits an Event for everything that happens to each Action as it happens.
+// The channel is closed once every Action has exited.
+//
+// Events are buffered without bound, so a slow consumer never blocks the resolve.
+// The consumer should drain the channel until it is closed, otherwise the buffered
+// events and the goroutine delivering them are never released.
+func (g *Graph) ResolveStreamWith(ctx context.Context, arg interface{}, opts ...ResolveOption) (<-chan Event, error) {
+	stream := newStreamRecorder()
+	s, err := g.resolve(ctx, arg, newResolveConfig(append(opts, WithRecorders(stream))))
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan Event)
+	go stream.pump(events)
+	go func() {
+		<-s.finished
+		stream.close()
+	}()
+
+	return events, nil
+}
+
+// streamRecorder is a Recorder that queues Events for delivery on a channel
+type streamRecorder struct {
+	mx     *sync.Mutex
+	cond   *sync.Cond
+	queue  []Event
+	closed bool
+
+	// id is the ID of the resolve, if any
+	id string
+}
+
+func newStreamRecorder() *streamRecorder {
+	mx := &sync.Mutex{}
+	return &streamRecorder{
+		mx:   mx,
+		cond: sync.NewCond(mx),
+	}
+}
+
+// WithID tags the events of this resolve with id.
+// A streamRecorder only ever records a single resolve.
+func (r *streamRecorder) WithID(id string) Recorder {
+	r.mx.Lock()
+	r.id = id
+	r.mx.Unlock()
+	return r
+}
+
+func (r *streamRecorder) push(e Event) {
+	r.mx.Lock()
+	e.ID = r.id
+	r.queue = append(r.queue, e)
+	r.cond.Signal()
+	r.mx.Unlock()
+}
+
+func (r *streamRecorder) close() {
+	r.mx.Lock()
+	r.closed = true
+	r.cond.Signal()
+	r.mx.Unlock()
+}
+
+// pump delivers queued events in order until the recorder is closed and drained
+func (r *streamRecorder) pump(events chan<- Event) {
+	for {
+		r.mx.Lock()
+		for len(r.queue) == 0 && !r.closed {
+			r.cond.Wait()
+		}
+		batch := r.queue
+		r.queue = nil
+		closed := r.closed
+		r.mx.Unlock()
+
+		for _, e := range batch {
+			events <- e
+		}
+		if closed && len(batch) == 0 {
+			close(events)
+			return
+		}
+	}
+}
+
+func (r *streamRecorder) Enter(name string) {
+	r.push(Event{Name: name, Kind: EventEnter})
+}
+
+func (r *streamRecorder) Start(name string) {
+	r.push(Event{Name: name, Kind: EventStart})
+}
+
+func (r *streamRecorder) Finish(name string) {
+	r.push(Event{Name: name, Kind: EventFinish})
+}
+
+func (r *streamRecorder) Exit(name string) {
+	r.push(Event{Name: name, Kind: EventExit})
+}
+
+func (r *streamRecorder) Error(name string, err error) {
+	r.push(Event{Name: name, Kind: EventError, Err: err})
+}
+
+func (r *streamRecorder) Swallow(name string, err error) {
+	r.push(Event{Name: name, Kind: EventSwallow, Err: err})
+}
+
+func (r *streamRecorder) Skip(name string) {
+	r.push(Event{Name: name, Kind: EventSkip})
+}
+
+func (r *streamRecorder) Gate(name string) {
+	r.push(Event{Name: name, Kind: EventGate})
+}
+
+func (r *streamRecorder) Retry(name string, attempt int, err error) {
+	r.push(Event{Name: name, Kind: EventRetry, Err: err, Attempt: attempt})
+}
+
+func (r *streamRecorder) GiveUp(name string, reason error) {
+	r.push(Event{Name: name, Kind: EventGiveUp, Err: reason})
+}
+
+func (r *streamRecorder) NoChange(name string) {
+	r.push(Event{Name: name, Kind: EventNoChange})
+}
+
+func (r *streamRecorder) Timeout(name string) {
+	r.push(Event{Name: name, Kind: EventTimeout})
+}
+
+func (r *streamRecorder) Panic(name string, v interface{}) {
+	r.push(Event{Name: name, Kind: EventPanic, Value: v})
+}
+
+func (r *streamRecorder) Abort(name string) {
+	r.push(Event{Name: name, Kind: EventAbort})
+}
@@ -0,0 +1,112 @@
+package depfunc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func collectEvents(events <-chan Event) []Event {
+	var out []Event
+	for e := range events {
+		out = append(out, e)
+	}
+	return out
+}
+
+func indexOfEvent(events []Event, name string, kind EventKind) int {
+	for i, e := range events {
+		if e.Name == name && e.Kind == kind {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestEventKind_String(t *testing.T) {
+	assert.Equal(t, "enter", EventEnter.String())
+	assert.Equal(t, "swallow", EventSwallow.String())
+	assert.Equal(t, "unknown", EventKind(-1).String())
+}
+
+func TestGraph_ResolveStream(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", visitorAction("a"))
+	g.AddAction("b", visitorAction("b"))
+	g.LinkDependency("a", "b")
+
+	events, err := g.ResolveStream(testContext(), newVisitordata())
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := collectEvents(events)
+
+	assert.Len(t, out, 8)
+	for _, name := range []string{"a", "b"} {
+		for _, kind := range []EventKind{EventEnter, EventStart, EventFinish, EventExit} {
+			assert.NotEqual(t, -1, indexOfEvent(out, name, kind), "missing %s %s", name, kind)
+		}
+	}
+	assert.True(t, indexOfEvent(out, "a", EventFinish) < indexOfEvent(out, "b", EventStart))
+}
+
+func TestGraph_ResolveStream_swallow(t *testing.T) {
+	g := NewGraph()
+	g.AddActionContinueOnError("a", failingAction("a"))
+
+	events, err := g.ResolveStream(testContext(), newVisitordata())
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := collectEvents(events)
+
+	i := indexOfEvent(out, "a", EventSwallow)
+	if assert.NotEqual(t, -1, i) {
+		assert.EqualError(t, out[i].Err, "failed a")
+	}
+}
+
+func TestGraph_ResolveStream_error(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", visitorAction("a"))
+	g.AddAction("b", visitorAction("b"))
+	g.LinkDependency("a", "b")
+	g.LinkDependency("b", "a")
+
+	events, err := g.ResolveStream(testContext(), newVisitordata())
+
+	assert.EqualError(t, err, "no roots in graph")
+	assert.Nil(t, events)
+}
+
+func TestGraph_ResolveStreamWith(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", visitorAction("a"))
+	g.AddAction("b", visitorAction("b"))
+	g.LinkDependency("a", "b")
+	stats := NewStatistics()
+
+	events, err := g.ResolveStreamWith(testContext(), newVisitordata(), WithSkip(skipNames("b")), WithRecorders(stats.Recorder()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := collectEvents(events)
+
+	assert.NotEqual(t, -1, indexOfEvent(out, "a", EventFinish))
+	assert.NotEqual(t, -1, indexOfEvent(out, "b", EventSkip))
+	assert.True(t, stats.Executed("a"))
+}
+
+func TestGraph_ResolveStreamWith_id(t *testing.T) {
+	g := NewGraph()
+	g.AddAction("a", visitorAction("a"))
+
+	events, err := g.ResolveStreamWith(testContext(), newVisitordata(), WithID("first"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, e := range collectEvents(events) {
+		assert.Equal(t, "first", e.ID)
+	}
+}
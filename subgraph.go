@@ -0,0 +1,139 @@
+package depfunc
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// AddSubgraph adds sub to the graph as a single action, which resolves sub
+// to completion with the same context and arg when it is executed.
+// The action finishes once every action in sub has exited, and fails with
+// the error that ended the resolve of sub early, if any. An empty sub does nothing.
+// A graph cannot contain itself, whether directly or through the subgraphs of sub.
+//
+// Events of the actions in sub are reported to the recorders of the resolve
+// with their names prefixed by name and a slash, such as "name/inner".
+//
+// sub is resolved with opts and nothing else: it does not inherit the executor,
+// Scheduler or other options of the resolve it is part of, so its actions execute
+// on goroutines of their own unless opts say otherwise. An executor given for sub
+// must not be one that the subgraph's action itself holds a slot of, or they can deadlock.
+func (g *Graph) AddSubgraph(name string, sub *Graph, opts ...ResolveOption) error {
+	if name == "" {
+		return ErrEmptyName
+	}
+	if sub == nil {
+		return errors.New("subgraph must not be nil")
+	}
+	if sub == g {
+		return errors.New("graph cannot be its own subgraph")
+	}
+	if sub.nests(g) {
+		return errors.Errorf("subgraph %q contains the graph", name)
+	}
+	action := func(ctx context.Context, arg interface{}) error {
+		subOpts := append([]ResolveOption{AllowEmpty()}, opts...)
+		if node, ok := NodeFromContext(ctx); ok {
+			subOpts = append(subOpts, WithRecorders(&prefixRecorder{prefix: name + "/", recorder: node.recorder}))
+		}
+		s, err := sub.resolve(ctx, arg, newResolveConfig(subOpts))
+		if err != nil {
+			return err
+		}
+		<-s.finished
+		return s.outcome.get()
+	}
+	defer g.change()()
+	g.addAction(name, action)
+	g.subgraphs[name] = sub
+	return nil
+}
+
+// nests returns if target is a subgraph of this graph, or of any of its subgraphs
+func (g *Graph) nests(target *Graph) bool {
+	visited := map[*Graph]bool{g: true}
+	pending := []*Graph{g}
+	for len(pending) > 0 {
+		next := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		next.mx.RLock()
+		subgraphs := make([]*Graph, 0, len(next.subgraphs))
+		for _, sub := range next.subgraphs {
+			subgraphs = append(subgraphs, sub)
+		}
+		next.mx.RUnlock()
+		for _, sub := range subgraphs {
+			if sub == target {
+				return true
+			}
+			if !visited[sub] {
+				visited[sub] = true
+				pending = append(pending, sub)
+			}
+		}
+	}
+	return false
+}
+
+// prefixRecorder is a Recorder that forwards every event to recorder with the name prefixed
+type prefixRecorder struct {
+	prefix   string
+	recorder Recorder
+}
+
+func (p *prefixRecorder) Enter(name string) {
+	p.recorder.Enter(p.prefix + name)
+}
+
+func (p *prefixRecorder) Start(name string) {
+	p.recorder.Start(p.prefix + name)
+}
+
+func (p *prefixRecorder) Finish(name string) {
+	p.recorder.Finish(p.prefix + name)
+}
+
+func (p *prefixRecorder) Exit(name string) {
+	p.recorder.Exit(p.prefix + name)
+}
+
+func (p *prefixRecorder) Error(name string, err error) {
+	recordError(p.recorder, p.prefix+name, err)
+}
+
+func (p *prefixRecorder) Swallow(name string, err error) {
+	recordSwallow(p.recorder, p.prefix+name, err)
+}
+
+func (p *prefixRecorder) Skip(name string) {
+	recordSkip(p.recorder, p.prefix+name)
+}
+
+func (p *prefixRecorder) Gate(name string) {
+	recordGate(p.recorder, p.prefix+name)
+}
+
+func (p *prefixRecorder) Retry(name string, attempt int, err error) {
+	recordRetry(p.recorder, p.prefix+name, attempt, err)
+}
+
+func (p *prefixRecorder) GiveUp(name string, reason error) {
+	recordGiveUp(p.recorder, p.prefix+name, reason)
+}
+
+func (p *prefixRecorder) NoChange(name string) {
+	recordNoChange(p.recorder, p.prefix+name)
+}
+
+func (p *prefixRecorder) Timeout(name string) {
+	recordTimeout(p.recorder, p.prefix+name)
+}
+
+func (p *prefixRecorder) Panic(name string, v interface{}) {
+	recordPanic(p.recorder, p.prefix+name, v)
+}
+
+func (p *prefixRecorder) Abort(name string) {
+	recordAbort(p.recorder, p.prefix+name)
+}
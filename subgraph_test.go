@@ -0,0 +1,123 @@
+package depfunc
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraph_AddSubgraph(t *testing.T) {
+	sub := NewGraph()
+	sub.AddAction("x", visitorAction("x"))
+	sub.AddAction("y", visitorAction("y"))
+	sub.LinkDependency("x", "y")
+
+	g := NewGraph()
+	g.AddAction("a", visitorAction("a"))
+	g.AddSubgraph("sub", sub)
+	g.AddAction("b", visitorAction("b"))
+	g.LinkDependency("a", "sub")
+	g.LinkDependency("sub", "b")
+
+	visitorData := newVisitordata()
+	events, err := g.ResolveStreamWith(testContext(), visitorData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := collectEvents(events)
+
+	assert.Equal(t, []string{"a", "x", "y", "b"}, visitorData.visited)
+	for _, name := range []string{"sub/x", "sub/y"} {
+		for _, kind := range []EventKind{EventEnter, EventStart, EventFinish, EventExit} {
+			assert.NotEqual(t, -1, indexOfEvent(out, name, kind), "missing %s %s", name, kind)
+		}
+	}
+	assert.True(t, indexOfEvent(out, "sub/y", EventExit) < indexOfEvent(out, "sub", EventFinish))
+}
+
+func TestGraph_AddSubgraph_error(t *testing.T) {
+	sub := NewGraph()
+	sub.AddActionE("x", failingAction("x"))
+
+	g := NewGraph()
+	g.AddSubgraph("sub", sub)
+	g.AddAction("b", visitorAction("b"))
+	g.LinkDependency("sub", "b")
+	recorder := newErrorRecorder()
+
+	visitorData := newVisitordata()
+	r, err := g.Start(testContext(), visitorData, WithRecorders(recorder))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.EqualError(t, r.Wait(), `action "sub": action "x": failed x`)
+	assert.EqualError(t, recorder.errors["sub/x"], "failed x")
+	assert.Equal(t, []string{"x"}, visitorData.visited)
+}
+
+func TestGraph_AddSubgraph_empty(t *testing.T) {
+	g := NewGraph()
+	g.AddSubgraph("sub", NewGraph())
+
+	r, err := g.Start(testContext(), newVisitordata())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, r.Wait())
+}
+
+func TestGraph_AddSubgraph_invalid(t *testing.T) {
+	g := NewGraph()
+
+	assert.EqualError(t, g.AddSubgraph("sub", nil), "subgraph must not be nil")
+	assert.EqualError(t, g.AddSubgraph("sub", g), "graph cannot be its own subgraph")
+	assert.Error(t, g.AddSubgraph("", NewGraph()))
+	assert.Len(t, g.actions, 0)
+}
+
+func TestGraph_AddSubgraph_nestedLoop(t *testing.T) {
+	a := NewGraph()
+	b := NewGraph()
+	c := NewGraph()
+	assert.NoError(t, a.AddSubgraph("b", b))
+	assert.NoError(t, b.AddSubgraph("c", c))
+
+	assert.EqualError(t, b.AddSubgraph("a", a), `subgraph "a" contains the graph`)
+	assert.EqualError(t, c.AddSubgraph("a", a), `subgraph "a" contains the graph`)
+	assert.NotContains(t, c.actions, "a")
+}
+
+func TestGraph_AddSubgraph_replaced(t *testing.T) {
+	a := NewGraph()
+	b := NewGraph()
+	a.AddSubgraph("b", b)
+	a.AddAction("b", sampleaction)
+
+	assert.NoError(t, b.AddSubgraph("a", a))
+}
+
+func TestGraph_AddSubgraph_options(t *testing.T) {
+	sub := NewGraph()
+	sub.AddAction("x", visitorAction("x"))
+	var submitted int32
+	executor := func(task func()) {
+		atomic.AddInt32(&submitted, 1)
+		go task()
+	}
+
+	g := NewGraph()
+	g.AddSubgraph("sub", sub, WithExecutor(executor))
+
+	visitorData := newVisitordata()
+	r, err := g.Start(testContext(), visitorData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, r.Wait())
+	assert.Equal(t, []string{"x"}, visitorData.visited)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&submitted))
+}
@@ -1,7 +1,13 @@
 package depfunc
 
-import "bytes"
+import (
+	"bytes"
+	"sort"
+	"strconv"
+	"sync"
+)
 
+// StringSet is a set of strings
 type StringSet map[string]struct{}
 
 func (ss StringSet) Contains(s string) bool {
@@ -17,19 +23,52 @@ func (ss StringSet) Remove(s string) {
 	delete(ss, s)
 }
 
+// Union returns a new set of the strings in either ss or other
+func (ss StringSet) Union(other StringSet) StringSet {
+	out := make(StringSet, len(ss)+len(other))
+	for s := range ss {
+		out.Add(s)
+	}
+	for s := range other {
+		out.Add(s)
+	}
+	return out
+}
+
+// Intersect returns a new set of the strings in both ss and other
+func (ss StringSet) Intersect(other StringSet) StringSet {
+	out := make(StringSet)
+	for s := range ss {
+		if other.Contains(s) {
+			out.Add(s)
+		}
+	}
+	return out
+}
+
+// Difference returns a new set of the strings in ss but not in other
+func (ss StringSet) Difference(other StringSet) StringSet {
+	out := make(StringSet)
+	for s := range ss {
+		if !other.Contains(s) {
+			out.Add(s)
+		}
+	}
+	return out
+}
+
+// String renders the strings of the set in sorted order, such as {"a","b"}
 func (ss StringSet) String() string {
 	buf := &bytes.Buffer{}
 	buf.WriteRune('{')
 
-	i := 1
-	for s := range ss {
+	for i, s := range sortedNames(ss) {
+		if i > 0 {
+			buf.WriteRune(',')
+		}
 		buf.WriteRune('"')
 		buf.WriteString(s)
 		buf.WriteRune('"')
-		if i < len(ss) {
-			buf.WriteRune(',')
-		}
-		i++
 	}
 	buf.WriteRune('}')
 
@@ -47,6 +86,24 @@ func (m stringmultimap) Add(key, value string) {
 	set.Add(value)
 }
 
+// Remove removes value from the set of key, removing key once its set is empty
+func (m stringmultimap) Remove(key, value string) {
+	set := m[key]
+	set.Remove(value)
+	if len(set) == 0 {
+		delete(m, key)
+	}
+}
+
+// copy returns a copy of m that shares none of its sets
+func (m stringmultimap) copy() stringmultimap {
+	c := make(stringmultimap, len(m))
+	for key, set := range m {
+		c[key] = set.Union(nil)
+	}
+	return c
+}
+
 type stringstack struct {
 	stack []string
 }
@@ -55,16 +112,126 @@ func (ss *stringstack) Push(s string) {
 	ss.stack = append(ss.stack, s)
 }
 
-func (ss *stringstack) Pop() string {
+// Pop removes and returns the string on top of the stack,
+// or returns false if the stack is empty
+func (ss *stringstack) Pop() (string, bool) {
 	l := len(ss.stack)
+	if l == 0 {
+		return "", false
+	}
 	top, bottom := ss.stack[l-1], ss.stack[:l-1]
 	ss.stack[l-1] = ""
 	ss.stack = bottom
-	return top
+	return top, true
 }
 
-func (ss *stringstack) Top() string {
+// Top returns the string on top of the stack,
+// or returns false if the stack is empty
+func (ss *stringstack) Top() (string, bool) {
 	l := len(ss.stack)
-	top := ss.stack[l-1]
-	return top
+	if l == 0 {
+		return "", false
+	}
+	return ss.stack[l-1], true
+}
+
+// sortedNames returns the members of ss in sorted order
+func sortedNames(ss StringSet) []string {
+	names := make([]string, 0, len(ss))
+	for s := range ss {
+		names = append(names, s)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// rankedNames returns the members of ss ordered by rank, such as the order they were added to a Graph
+func rankedNames(ss StringSet, rank map[string]int) []string {
+	names := make([]string, 0, len(ss))
+	for s := range ss {
+		names = append(names, s)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return rank[names[i]] < rank[names[j]]
+	})
+	return names
+}
+
+// SyncStringSet is a StringSet that is safe for concurrent use
+type SyncStringSet struct {
+	mx  *sync.RWMutex
+	set StringSet
+}
+
+// NewSyncStringSet creates a new, empty SyncStringSet
+func NewSyncStringSet() *SyncStringSet {
+	return &SyncStringSet{
+		mx:  &sync.RWMutex{},
+		set: make(StringSet),
+	}
+}
+
+func (ss *SyncStringSet) Contains(s string) bool {
+	ss.mx.RLock()
+	defer ss.mx.RUnlock()
+	return ss.set.Contains(s)
+}
+
+func (ss *SyncStringSet) Add(s string) {
+	ss.mx.Lock()
+	ss.set.Add(s)
+	ss.mx.Unlock()
+}
+
+func (ss *SyncStringSet) Remove(s string) {
+	ss.mx.Lock()
+	ss.set.Remove(s)
+	ss.mx.Unlock()
+}
+
+// Len returns the number of strings in the set
+func (ss *SyncStringSet) Len() int {
+	ss.mx.RLock()
+	defer ss.mx.RUnlock()
+	return len(ss.set)
+}
+
+// Copy returns a StringSet of the strings currently in the set
+func (ss *SyncStringSet) Copy() StringSet {
+	ss.mx.RLock()
+	defer ss.mx.RUnlock()
+	return ss.set.Union(nil)
+}
+
+// Union returns a new set of the strings in either ss or other
+func (ss *SyncStringSet) Union(other StringSet) StringSet {
+	ss.mx.RLock()
+	defer ss.mx.RUnlock()
+	return ss.set.Union(other)
+}
+
+// Intersect returns a new set of the strings in both ss and other
+func (ss *SyncStringSet) Intersect(other StringSet) StringSet {
+	ss.mx.RLock()
+	defer ss.mx.RUnlock()
+	return ss.set.Intersect(other)
+}
+
+// Difference returns a new set of the strings in ss but not in other
+func (ss *SyncStringSet) Difference(other StringSet) StringSet {
+	ss.mx.RLock()
+	defer ss.mx.RUnlock()
+	return ss.set.Difference(other)
+}
+
+func (ss *SyncStringSet) String() string {
+	ss.mx.RLock()
+	defer ss.mx.RUnlock()
+	return ss.set.String()
+}
+
+// IndexedName returns a predictable name for the i-th of many generated
+// actions, such as IndexedName("node", 7) == "node7"
+func IndexedName(prefix string, i int) string {
+	return prefix + strconv.Itoa(i)
 }
@@ -1,6 +1,8 @@
 package depfunc
 
 import (
+	"strconv"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -56,6 +58,20 @@ func TestStringmultimap_AddToSet(t *testing.T) {
 	assert.Len(t, m["test1"], 2)
 }
 
+func TestStringmultimap_Remove(t *testing.T) {
+	m := make(stringmultimap)
+
+	m.Add("test1", "1")
+	m.Add("test1", "2")
+	m.Remove("test1", "1")
+
+	assert.Equal(t, stringSet("2"), m["test1"])
+
+	m.Remove("test1", "2")
+
+	assert.Len(t, m, 0)
+}
+
 func TestStringset_Add(t *testing.T) {
 	s := make(StringSet)
 
@@ -87,6 +103,62 @@ func TestStringset_Contains(t *testing.T) {
 	assert.True(t, s.Contains("a"))
 }
 
+func stringSet(values ...string) StringSet {
+	s := make(StringSet)
+	for _, v := range values {
+		s.Add(v)
+	}
+	return s
+}
+
+func TestStringset_Union(t *testing.T) {
+	a := stringSet("a", "b")
+	b := stringSet("b", "c")
+
+	u := a.Union(b)
+
+	assert.Equal(t, stringSet("a", "b", "c"), u)
+	assert.Equal(t, stringSet("a", "b"), a)
+	assert.Equal(t, stringSet("b", "c"), b)
+}
+
+func TestStringset_Union_empty(t *testing.T) {
+	a := stringSet("a")
+
+	u := a.Union(nil)
+
+	assert.Equal(t, stringSet("a"), u)
+}
+
+func TestStringset_Intersect(t *testing.T) {
+	a := stringSet("a", "b")
+	b := stringSet("b", "c")
+
+	i := a.Intersect(b)
+
+	assert.Equal(t, stringSet("b"), i)
+	assert.Equal(t, stringSet("a", "b"), a)
+}
+
+func TestStringset_Intersect_disjoint(t *testing.T) {
+	a := stringSet("a")
+	b := stringSet("b")
+
+	i := a.Intersect(b)
+
+	assert.Len(t, i, 0)
+}
+
+func TestStringset_Difference(t *testing.T) {
+	a := stringSet("a", "b")
+	b := stringSet("b", "c")
+
+	d := a.Difference(b)
+
+	assert.Equal(t, stringSet("a"), d)
+	assert.Equal(t, stringSet("a", "b"), a)
+}
+
 func TestStringset_String(t *testing.T) {
 	s := make(StringSet)
 
@@ -105,20 +177,14 @@ func TestStringset_String_value(t *testing.T) {
 }
 
 func TestStringset_String_values(t *testing.T) {
-	// because of the non-deterministic ordering of map, "a" or "b" could come first
-	const (
-		aFirst = `{"a","b"}`
-		bFirst = `{"b","a"}`
-	)
 	s := make(StringSet)
+	s.Add("c")
 	s.Add("a")
 	s.Add("b")
 
 	out := s.String()
 
-	if out != aFirst && out != bFirst {
-		t.Errorf("unexpected string want %s or %s, got %s", aFirst, bFirst, out)
-	}
+	assert.Equal(t, `{"a","b","c"}`, out)
 }
 
 func TestStringstack_Push(t *testing.T) {
@@ -135,17 +201,115 @@ func TestStringstack_Pop(t *testing.T) {
 	ss := &stringstack{}
 
 	ss.Push("a")
-	a := ss.Pop()
+	a, ok := ss.Pop()
 
 	assert.Len(t, ss.stack, 0)
 	assert.Equal(t, "a", a)
+	assert.True(t, ok)
+}
+
+func TestStringstack_Pop_empty(t *testing.T) {
+	ss := &stringstack{}
+
+	ss.Push("a")
+	ss.Pop()
+	a, ok := ss.Pop()
+
+	assert.Len(t, ss.stack, 0)
+	assert.Equal(t, "", a)
+	assert.False(t, ok)
 }
 
 func TestStringstack_Top(t *testing.T) {
 	ss := &stringstack{}
 
 	ss.Push("a")
-	a := ss.Top()
+	a, ok := ss.Top()
 
 	assert.Equal(t, "a", a)
+	assert.True(t, ok)
+	assert.Len(t, ss.stack, 1)
+}
+
+func TestStringstack_Top_empty(t *testing.T) {
+	ss := &stringstack{}
+
+	a, ok := ss.Top()
+
+	assert.Equal(t, "", a)
+	assert.False(t, ok)
+}
+
+func TestSortedNames(t *testing.T) {
+	s := make(StringSet)
+	s.Add("b")
+	s.Add("c")
+	s.Add("a")
+
+	names := sortedNames(s)
+
+	assert.Equal(t, []string{"a", "b", "c"}, names)
+}
+
+func TestSyncStringSet_New(t *testing.T) {
+	s := NewSyncStringSet()
+
+	assert.Equal(t, 0, s.Len())
+	assert.Equal(t, "{}", s.String())
+}
+
+func TestSyncStringSet_AddRemoveContains(t *testing.T) {
+	s := NewSyncStringSet()
+
+	s.Add("a")
+	assert.True(t, s.Contains("a"))
+	assert.Equal(t, 1, s.Len())
+
+	s.Remove("a")
+	assert.False(t, s.Contains("a"))
+	assert.Equal(t, 0, s.Len())
+}
+
+func TestSyncStringSet_Copy(t *testing.T) {
+	s := NewSyncStringSet()
+	s.Add("a")
+
+	c := s.Copy()
+	s.Add("b")
+
+	assert.Equal(t, stringSet("a"), c)
+}
+
+func TestSyncStringSet_SetOperations(t *testing.T) {
+	s := NewSyncStringSet()
+	s.Add("a")
+	s.Add("b")
+	other := stringSet("b", "c")
+
+	assert.Equal(t, stringSet("a", "b", "c"), s.Union(other))
+	assert.Equal(t, stringSet("b"), s.Intersect(other))
+	assert.Equal(t, stringSet("a"), s.Difference(other))
+}
+
+func TestSyncStringSet_concurrent(t *testing.T) {
+	s := NewSyncStringSet()
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := strconv.Itoa(i)
+			s.Add(name)
+			s.Contains(name)
+			_ = s.String()
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 100, s.Len())
+}
+
+func TestIndexedName(t *testing.T) {
+	assert.Equal(t, "node7", IndexedName("node", 7))
+	assert.Equal(t, "12", IndexedName("", 12))
 }
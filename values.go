@@ -0,0 +1,130 @@
+package depfunc
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// SetContextValue sets a value that the Actions depending on the current Action
+// can read from their context with ctx.Value(key), without sharing the Resolve arg.
+//
+// The values flow transitively: an Action's dependents are given the values its
+// own dependencies set, along with those it set itself, which replace any of the
+// same key. When the dependencies of an Action set the same key, the value of the
+// dependency that finished last is the one its context carries. Actions added by
+// Node.AddDependent are not given any values.
+//
+// Like context.WithValue, key must be comparable and should be of a type of its own.
+// SetContextValue may only be called while the current Action is executing: once
+// the Action returns, an error is returned.
+func (n *Node) SetContextValue(key, val interface{}) error {
+	if key == nil {
+		return errors.New("key must not be nil")
+	}
+	if !reflect.TypeOf(key).Comparable() {
+		return errors.Errorf("key of type %T is not comparable", key)
+	}
+
+	n.mx.Lock()
+	defer n.mx.Unlock()
+	if n.sealed {
+		return errors.Errorf("action %q has already finished", n.name)
+	}
+	if n.values == nil {
+		n.values = make(map[interface{}]interface{}, len(n.inherited)+1)
+		for k, v := range n.inherited {
+			n.values[k] = v
+		}
+	}
+	n.values[key] = val
+	return nil
+}
+
+// valuesContext is a context carrying the values set by the dependencies of an Action
+type valuesContext struct {
+	context.Context
+	values map[interface{}]interface{}
+}
+
+// withValues returns a context carrying values, or ctx if there are none
+func withValues(ctx context.Context, values map[interface{}]interface{}) context.Context {
+	if len(values) == 0 {
+		return ctx
+	}
+	return &valuesContext{Context: ctx, values: values}
+}
+
+func (c *valuesContext) Value(key interface{}) interface{} {
+	if val, ok := c.values[key]; ok {
+		return val
+	}
+	return c.Context.Value(key)
+}
+
+// nodeValues holds the values each finished Action passes to its dependents,
+// in the order the Actions finished
+type nodeValues struct {
+	mx        *sync.Mutex
+	published map[string]publishedValues
+	finished  int
+}
+
+// publishedValues are the values of an Action and when it finished relative to the others
+type publishedValues struct {
+	values   map[interface{}]interface{}
+	finished int
+}
+
+func newNodeValues() *nodeValues {
+	return &nodeValues{
+		mx:        &sync.Mutex{},
+		published: make(map[string]publishedValues),
+	}
+}
+
+// publish records the values name passes to its dependents once it has finished
+func (v *nodeValues) publish(name string, values map[interface{}]interface{}) {
+	if len(values) == 0 {
+		return
+	}
+	v.mx.Lock()
+	v.finished++
+	v.published[name] = publishedValues{values: values, finished: v.finished}
+	v.mx.Unlock()
+}
+
+// merge returns the values passed by dependencies, those of the dependency
+// that finished last replacing any of the same key
+func (v *nodeValues) merge(dependencies StringSet) map[interface{}]interface{} {
+	v.mx.Lock()
+	defer v.mx.Unlock()
+	if len(v.published) == 0 {
+		return nil
+	}
+	var ordered []publishedValues
+	for dependency := range dependencies {
+		if published, ok := v.published[dependency]; ok {
+			ordered = append(ordered, published)
+		}
+	}
+	switch len(ordered) {
+	case 0:
+		return nil
+	case 1:
+		return ordered[0].values
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		return ordered[i].finished < ordered[j].finished
+	})
+	merged := make(map[interface{}]interface{})
+	for _, published := range ordered {
+		for key, val := range published.values {
+			merged[key] = val
+		}
+	}
+	return merged
+}
@@ -0,0 +1,104 @@
+package depfunc
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type valueKey string
+
+// settingAction sets key to val for its dependents
+func settingAction(key valueKey, val string) ActionE {
+	return func(ctx context.Context, arg interface{}) error {
+		node, _ := NodeFromContext(ctx)
+		return node.SetContextValue(key, val)
+	}
+}
+
+// readingAction records the value of key in its context under the Action's name
+func readingAction(read *sync.Map, key valueKey) Action {
+	return func(ctx context.Context, arg interface{}) {
+		name, _ := NameFromContext(ctx)
+		read.Store(name, ctx.Value(key))
+	}
+}
+
+// resolveWait resolves g and waits for every Action to exit
+func resolveWait(g *Graph) error {
+	r, err := g.Start(testContext(), nil)
+	if err != nil {
+		return err
+	}
+	return r.Wait()
+}
+
+func TestNode_SetContextValue(t *testing.T) {
+	read := &sync.Map{}
+	g := NewGraph()
+	g.AddActionE("a", settingAction("k", "a"))
+	g.AddAction("b", readingAction(read, "k"))
+	g.AddAction("c", readingAction(read, "k"))
+	g.AddAction("d", readingAction(read, "k"))
+	g.LinkDependency("a", "b")
+	g.LinkDependency("b", "c")
+
+	assert.NoError(t, resolveWait(g))
+
+	b, _ := read.Load("b")
+	c, _ := read.Load("c")
+	d, _ := read.Load("d")
+	assert.Equal(t, "a", b)
+	assert.Equal(t, "a", c)
+	assert.Nil(t, d)
+}
+
+func TestNode_SetContextValue_override(t *testing.T) {
+	read := &sync.Map{}
+	g := NewGraph()
+	g.AddActionE("a", settingAction("k", "a"))
+	g.AddActionE("b", settingAction("k", "b"))
+	g.AddAction("c", readingAction(read, "k"))
+	g.LinkDependency("a", "b")
+	g.LinkDependency("b", "c")
+
+	assert.NoError(t, resolveWait(g))
+
+	c, _ := read.Load("c")
+	assert.Equal(t, "b", c)
+}
+
+func TestNode_SetContextValue_lastFinished(t *testing.T) {
+	read := &sync.Map{}
+	g := NewGraph()
+	g.AddActionE("first", settingAction("k", "first"))
+	g.AddActionE("second", func(ctx context.Context, arg interface{}) error {
+		time.Sleep(20 * time.Millisecond)
+		return settingAction("k", "second")(ctx, arg)
+	})
+	g.AddAction("c", readingAction(read, "k"))
+	g.LinkDependency("first", "c")
+	g.LinkDependency("second", "c")
+
+	assert.NoError(t, resolveWait(g))
+
+	c, _ := read.Load("c")
+	assert.Equal(t, "second", c)
+}
+
+func TestNode_SetContextValue_errors(t *testing.T) {
+	var node *Node
+	g := NewGraph()
+	g.AddActionE("a", func(ctx context.Context, arg interface{}) error {
+		node, _ = NodeFromContext(ctx)
+		assert.EqualError(t, node.SetContextValue(nil, "v"), "key must not be nil")
+		assert.EqualError(t, node.SetContextValue([]string{}, "v"), "key of type []string is not comparable")
+		return nil
+	})
+
+	assert.NoError(t, resolveWait(g))
+	assert.EqualError(t, node.SetContextValue(valueKey("k"), "v"), `action "a" has already finished`)
+}